@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/librsa"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher"
+	"github.com/grinderz/grgo/patcher/libmodsig"
+)
+
+var (
+	errVerifyMismatch = errors.New("verify found mismatched patterns")
+	errSignHash       = errors.New("unsupported signature hash")
+	errPadding        = errors.New("padding must be <segment>=<policy>")
+)
+
+//nolint:gochecknoglobals
+var signHashes = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+//nolint:gochecknoglobals
+var signatureActions = map[patcher.SignaturePolicyEnum]string{
+	patcher.SignaturePolicyKeep:   "invalidated",
+	patcher.SignaturePolicyStrip:  "stripped",
+	patcher.SignaturePolicyResign: "resigned",
+}
+
+type patchFlags struct {
+	patterns  string
+	kernel    string
+	backup    bool
+	backupPol patcher.BackupPolicy
+	workers   int
+	mode      patcher.BatchModeEnum
+	stream    bool
+	streams   bool
+	padding   map[string]patcher.PaddingPolicyEnum
+	keepTemp  bool
+	maxSize   int64
+	limits    libio.UnpackLimits
+	json      bool
+	signature patcher.SignaturePolicyEnum
+	signKey   string
+	signCert  string
+	signHash  string
+	digest    patcher.Digest
+	imgSign   string
+	imgVerify string
+	imgScheme librsa.SchemeEnum
+	imgHash   string
+}
+
+func newFlagSet(name string, app *app) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(app.stderr)
+
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string, minArgs int) error {
+	if err := flags.Parse(args); err != nil {
+		return errors.Join(errUsage, err)
+	}
+
+	if flags.NArg() < minArgs {
+		return errUsage
+	}
+
+	return nil
+}
+
+func (f *patchFlags) register(flags *flag.FlagSet, batch bool) {
+	flags.StringVar(&f.patterns, "patterns", "", "pattern set `file` (yaml or json)")
+	flags.StringVar(&f.kernel, "kernel", "", "kernel `version` used to select patterns")
+
+	if !batch {
+		return
+	}
+
+	f.mode = patcher.BatchModeBestEffort
+
+	flags.BoolVar(&f.backup, "backup", false, "keep a copy of the original image in <image>.bak")
+	flags.StringVar(&f.backupPol.Dir, "backup-dir", "", "write backups to `dir` instead of next to the image")
+	flags.BoolVar(&f.backupPol.Timestamp, "backup-timestamp", false, "name backups <image>.<time>.bak")
+	flags.IntVar(&f.backupPol.Keep, "backup-keep", 0, "keep the newest `n` timestamped backups, 0 keeps all")
+	flags.BoolVar(&f.backupPol.NoOverwrite, "backup-no-overwrite", false, "fail instead of overwriting an existing backup")
+	flags.BoolVar(&f.backupPol.Compress, "backup-compress", false, "gzip backups")
+	flags.IntVar(&f.workers, "workers", 0, "max images patched at once, 0 means GOMAXPROCS")
+	flags.TextVar(&f.mode, "mode", f.mode, "best-effort or fail-fast")
+	flags.BoolVar(&f.json, "json", false, "print results as json lines")
+	f.limits.MaxSize = libio.DefaultMaxUnpackSize
+
+	flags.Int64Var(&f.limits.MaxSize, "max-unpack-size", f.limits.MaxSize, "fail when a payload, all members or streams together, decompresses to more than `bytes`, 0 disables it")
+	flags.Int64Var(&f.limits.MaxRatio, "max-ratio", 0, "fail when a payload decompresses over `n` times its compressed size, 0 disables it")
+	flags.Func("max-dict-size", "max xz dictionary `bytes`, defaults to 64 MiB", func(value string) error {
+		size, err := strconv.ParseUint(value, 10, 32)
+		f.limits.MaxDictSize = uint32(size)
+
+		return err //nolint:wrapcheck
+	})
+	flags.Int64Var(&f.maxSize, "max-size", 0, "fail when a repacked image exceeds `bytes`, 0 disables it")
+	flags.BoolVar(&f.keepTemp, "keep-temp", false, "keep temp workspaces for debugging")
+	flags.BoolVar(&f.streams, "preserve-streams", false, "repack multi-member gzip or multi-stream xz payloads into as many ones, keeping their compression")
+	flags.Func("padding", "restore the trailing padding of `segment=policy` with align, keep or drop, repeatable", func(value string) error {
+		segment, name, found := strings.Cut(value, "=")
+		if !found {
+			return errPadding
+		}
+
+		var policy patcher.PaddingPolicyEnum
+		if err := policy.SetValue(name); err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		if f.padding == nil {
+			f.padding = make(map[string]patcher.PaddingPolicyEnum)
+		}
+
+		f.padding[segment] = policy
+
+		return nil
+	})
+	flags.BoolVar(&f.stream, "stream", false, "patch in a single streaming pass, patterns must not use member or elf")
+	flags.TextVar(&f.digest, "expect-hash", f.digest, "refuse images not matching `digest` sha256:<hex> or sha512:<hex>")
+
+	f.signature = patcher.SignaturePolicyKeep
+
+	flags.TextVar(&f.signature, "signature", f.signature, "patched module signatures: keep, strip or resign")
+	flags.StringVar(&f.signKey, "sign-key", "", "module signing key pem `file` used by resign")
+	flags.StringVar(&f.signCert, "sign-cert", "", "module signing certificate pem `file`, defaults to the key file")
+	flags.StringVar(&f.signHash, "sign-hash", "sha256", "module signature hash: sha1, sha256, sha384 or sha512")
+
+	f.imgScheme = librsa.SchemePKCS1v15
+
+	flags.StringVar(&f.imgSign, "image-sign-key", "", "write <image>.sig signed with the rsa key pem `file`")
+	flags.StringVar(&f.imgVerify, "image-verify-key", "", "verify <image>.sig with the rsa public key or certificate pem `file`")
+	flags.TextVar(&f.imgScheme, "image-scheme", f.imgScheme, "image signature scheme: pkcs1v15 or pss")
+	flags.StringVar(&f.imgHash, "image-hash", "sha256", "image signature hash: sha1, sha256, sha384 or sha512")
+}
+
+func (f *patchFlags) signer() (*libmodsig.Signer, error) {
+	if f.signature != patcher.SignaturePolicyResign {
+		return nil, nil
+	}
+
+	if len(f.signKey) == 0 {
+		return nil, errUsage
+	}
+
+	hash, ok := signHashes[f.signHash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errSignHash, f.signHash)
+	}
+
+	return libmodsig.LoadSigner(f.signKey, f.signCert, hash)
+}
+
+func (f *patchFlags) imageSignature() (*librsa.Signer, *librsa.Verifier, error) {
+	if len(f.imgSign) == 0 && len(f.imgVerify) == 0 {
+		return nil, nil, nil
+	}
+
+	hash, ok := signHashes[f.imgHash]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", errSignHash, f.imgHash)
+	}
+
+	var (
+		signer   *librsa.Signer
+		verifier *librsa.Verifier
+		err      error
+	)
+
+	if len(f.imgSign) > 0 {
+		if signer, err = librsa.LoadSigner(f.imgSign, hash, f.imgScheme); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(f.imgVerify) > 0 {
+		if verifier, err = librsa.LoadVerifier(f.imgVerify, hash, f.imgScheme); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return signer, verifier, nil
+}
+
+func (f *patchFlags) load() ([]*patcher.Pattern, error) {
+	if len(f.patterns) == 0 {
+		return nil, errUsage
+	}
+
+	set, err := patcher.LoadPatternSet(f.patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return set.Select(f.kernel)
+}
+
+// withPatcher runs fn with a patcher using the configured temp directory.
+func withPatcher(app *app, path string, fn func(p *cpiopatcher.Patcher) error) error {
+	return fn(cpiopatcher.New(app.cfg.TempDir, path, nil, app.logger))
+}
+
+func runInspect(ctx context.Context, app *app, args []string) error {
+	flags := newFlagSet("inspect", app)
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(app.stdout, 0, 0, 2, ' ', 0)
+
+	for _, path := range flags.Args() {
+		err := withPatcher(app, path, func(p *cpiopatcher.Patcher) error {
+			layout, err := p.Inspect(ctx)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(out, "path:\t%s\n", layout.Path)
+			fmt.Fprintf(out, "size:\t%d\n", layout.Size)
+			fmt.Fprintf(out, "cpio header:\t%d\n", layout.CPIOHeaderSize)
+			fmt.Fprintf(out, "cpio zero footer:\t%d\n", layout.CPIOZeroFooterSize)
+			fmt.Fprintf(out, "compression:\t%s\n", layout.Compression)
+			fmt.Fprintf(out, "compressed offset:\t%d\n", layout.CompressedOffset)
+			fmt.Fprintf(out, "compressed size:\t%d\n", layout.CompressedSize)
+			fmt.Fprintf(out, "streams:\t%d\n", layout.Streams)
+			fmt.Fprintf(out, "padding:\t%d\n", layout.Padding)
+			fmt.Fprintf(out, "align:\t%d\n", layout.Align)
+			fmt.Fprintf(out, "raw size:\t%d\n\n", layout.RawSize)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+func runList(ctx context.Context, app *app, args []string) error {
+	flags := newFlagSet("list", app)
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	return withPatcher(app, flags.Arg(0), func(p *cpiopatcher.Patcher) error {
+		members, err := p.Members(ctx)
+		if err != nil {
+			return err
+		}
+
+		out := tabwriter.NewWriter(app.stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+		for _, member := range members {
+			fmt.Fprintf(out, "%02o%04o\t%d\t%d\t %s\n", member.Type, member.Mode, member.Size, member.Offset, member.Name)
+		}
+
+		return out.Flush()
+	})
+}
+
+func runExtract(ctx context.Context, app *app, args []string) error {
+	flags := newFlagSet("extract", app)
+	member := flags.String("member", "", "archive member `name`")
+	output := flags.String("output", "", "output `file`, stdout when empty")
+
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	if len(*member) == 0 {
+		return errUsage
+	}
+
+	var dst io.Writer = app.stdout
+
+	if len(*output) > 0 {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("create output failed: %w", err)
+		}
+
+		defer file.Close()
+
+		dst = file
+	}
+
+	return withPatcher(app, flags.Arg(0), func(p *cpiopatcher.Patcher) error {
+		_, err := p.Extract(ctx, *member, dst)
+
+		return err
+	})
+}
+
+func runPatch(ctx context.Context, app *app, args []string) error {
+	return patch(ctx, app, "patch", args, false)
+}
+
+func runUnpatch(ctx context.Context, app *app, args []string) error {
+	return patch(ctx, app, "unpatch", args, true)
+}
+
+func patch(ctx context.Context, app *app, name string, args []string, reverse bool) error {
+	var pf patchFlags
+
+	flags := newFlagSet(name, app)
+	pf.register(flags, true)
+
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	patterns, err := pf.load()
+	if err != nil {
+		return err
+	}
+
+	if reverse {
+		patterns = patcher.ReversePatterns(patterns)
+	}
+
+	signer, err := pf.signer()
+	if err != nil {
+		return err
+	}
+
+	imgSigner, imgVerifier, err := pf.imageSignature()
+	if err != nil {
+		return err
+	}
+
+	opts := []cpiopatcher.Option{
+		cpiopatcher.WithModuleSignature(pf.signature, signer),
+		cpiopatcher.WithStreaming(pf.stream),
+		cpiopatcher.WithPreserveStreams(pf.streams),
+		cpiopatcher.WithKeepTemp(pf.keepTemp),
+		cpiopatcher.WithMaxOutputSize(pf.maxSize),
+		cpiopatcher.WithExpectedDigest(pf.digest),
+		cpiopatcher.WithImageSignature(imgSigner, imgVerifier),
+		cpiopatcher.WithUnpackLimits(pf.limits),
+		cpiopatcher.WithBackupPolicy(pf.backupPol),
+	}
+
+	for segment, policy := range pf.padding {
+		opts = append(opts, cpiopatcher.WithPaddingPolicy(segment, policy))
+	}
+
+	results := cpiopatcher.PatchBatch(
+		ctx, app.cfg.TempDir, flags.Args(), patterns, pf.backup, pf.workers, pf.mode, app.logger, opts...,
+	)
+
+	if pf.json {
+		encoder := json.NewEncoder(app.stdout)
+
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return fmt.Errorf("result encode failed: %w", err)
+			}
+		}
+
+		return patcher.JoinErrors(results)
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(app.stdout, "%s: failed\n", result.Path)
+			continue
+		}
+
+		fmt.Fprintf(app.stdout, "%s: %d bytes patched\n", result.Path, result.BytesPatched)
+
+		if len(result.Backup) > 0 {
+			fmt.Fprintf(app.stdout, "%s: backup %s\n", result.Path, result.Backup)
+		}
+
+		for _, member := range result.Signed {
+			fmt.Fprintf(app.stdout, "%s: %s: module signature %s\n", result.Path, member, signatureActions[pf.signature])
+		}
+	}
+
+	return patcher.JoinErrors(results)
+}
+
+func runVerify(ctx context.Context, app *app, args []string) error {
+	var pf patchFlags
+
+	flags := newFlagSet("verify", app)
+	pf.register(flags, false)
+
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	patterns, err := pf.load()
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(app.stdout, 0, 0, 2, ' ', 0)
+	mismatch := false
+
+	for _, path := range flags.Args() {
+		err := withPatcher(app, path, func(p *cpiopatcher.Patcher) error {
+			states, err := p.Verify(ctx, patterns)
+			if err != nil {
+				return err
+			}
+
+			for _, state := range states {
+				mismatch = mismatch || state.State == patcher.PatternStateMismatch
+
+				fmt.Fprintf(out, "%s\t%d\t%s\tsearch=%d\treplace=%d\t%s\n",
+					path, state.Index, state.State, state.SearchCount, state.ReplaceCount, state.Description)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := out.Flush(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if mismatch {
+		return errVerifyMismatch
+	}
+
+	return nil
+}
+
+func runRepack(ctx context.Context, app *app, args []string) error {
+	flags := newFlagSet("repack", app)
+	backup := flags.Bool("backup", false, "keep a copy of the original image in <image>.bak")
+
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	for _, path := range flags.Args() {
+		if err := withPatcher(app, path, func(p *cpiopatcher.Patcher) error {
+			return p.Repack(ctx, *backup)
+		}); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(app.stdout, "%s: repacked\n", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/patcher"
+)
+
+const testPatterns = `format: 1
+patterns:
+  - description: hello
+    member: etc/a.txt
+    count: 2
+    search: "48 45 4c 4c 4f 5f 57 4f 52 4c 44"
+    replace: "48 45 4c 4c 4f 5f 54 48 45 52 45"
+`
+
+func writePatterns(t *testing.T, dir string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "patterns.yaml")
+	if err := os.WriteFile(path, []byte(testPatterns), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestPatchFlags(t *testing.T) {
+	t.Parallel()
+
+	var pf patchFlags
+
+	flags := flag.NewFlagSet("patch", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	pf.register(flags, true)
+
+	err := parseFlags(flags, []string{
+		"-patterns", "set.yaml",
+		"-mode", "fail-fast",
+		"-padding", "payload=keep",
+		"-max-dict-size", "1024",
+		"-signature", "strip",
+		"image",
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pf.patterns != "set.yaml" || pf.mode != patcher.BatchModeFailFast || pf.signature != patcher.SignaturePolicyStrip {
+		t.Fatalf("flags non valid: %+v", pf)
+	}
+
+	if pf.padding["payload"] != patcher.PaddingPolicyKeep || pf.limits.MaxDictSize != 1024 {
+		t.Fatalf("flags non valid: %+v", pf)
+	}
+
+	if pf.limits.MaxSize != libio.DefaultMaxUnpackSize {
+		t.Fatalf("default max unpack size non valid: %d", pf.limits.MaxSize)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{name: "padding without policy", args: []string{"-padding", "payload", "image"}, msg: errPadding.Error()},
+		{name: "unknown padding policy", args: []string{"-padding", "payload=pad", "image"}},
+		{name: "unknown mode", args: []string{"-mode", "sometimes", "image"}},
+		{name: "missing image", args: nil},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var pf patchFlags
+
+			flags := flag.NewFlagSet("patch", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			pf.register(flags, true)
+
+			err := parseFlags(flags, test.args, 1)
+			if !errors.Is(err, errUsage) {
+				t.Fatalf("expected usage error, got %v", err)
+			}
+
+			if !strings.Contains(err.Error(), test.msg) {
+				t.Fatalf("expected %s, got %v", test.msg, err)
+			}
+		})
+	}
+}
+
+func TestRunList(t *testing.T) {
+	t.Parallel()
+
+	dir, image := newTestDir(t)
+
+	code, stdout, stderr := runTest(t, dir, "list", image)
+	if code != exitOK {
+		t.Fatalf("exit code non valid: %d, stderr: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, "etc/a.txt") {
+		t.Fatalf("members non valid: %s", stdout)
+	}
+}
+
+func TestRunExtract(t *testing.T) {
+	t.Parallel()
+
+	dir, image := newTestDir(t)
+	output := filepath.Join(dir, "a.txt")
+
+	code, _, stderr := runTest(t, dir, "extract", "-member", "etc/a.txt", "-output", output, image)
+	if code != exitOK {
+		t.Fatalf("exit code non valid: %d, stderr: %s", code, stderr)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, _ := runTest(t, dir, "extract", "-member", "/etc/a.txt", image)
+	if code != exitOK || stdout != string(data) || strings.Count(stdout, "HELLO_WORLD") != 2 {
+		t.Fatalf("member data non valid: %q %q", data, stdout)
+	}
+
+	if code, _, _ := runTest(t, dir, "extract", "-member", "etc/missing.txt", image); code != exitError {
+		t.Fatalf("missing member exit code non valid: %d", code)
+	}
+}
+
+func TestRunPatch(t *testing.T) {
+	t.Parallel()
+
+	dir, image := newTestDir(t)
+	patterns := writePatterns(t, dir)
+
+	original, err := os.ReadFile(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runTest(t, dir, "patch", "-patterns", patterns, "-backup", image)
+	if code != exitOK {
+		t.Fatalf("exit code non valid: %d, stderr: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, image+": 22 bytes patched") || !strings.Contains(stdout, image+": backup ") {
+		t.Fatalf("output non valid: %s", stdout)
+	}
+
+	backup, err := os.ReadFile(image + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(backup, original) {
+		t.Fatal("backup differs from original")
+	}
+
+	code, stdout, _ = runTest(t, dir, "verify", "-patterns", patterns, image)
+	if code != exitOK || !strings.Contains(stdout, patcher.PatternStatePatched.String()) {
+		t.Fatalf("verify non valid: %d %s", code, stdout)
+	}
+
+	code, stdout, _ = runTest(t, dir, "patch", "-patterns", patterns, "-json", image)
+	if code != exitError || !strings.Contains(stdout, `"path":"`+image+`"`) {
+		t.Fatalf("patch of patched image non valid: %d %s", code, stdout)
+	}
+}
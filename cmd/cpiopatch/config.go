@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ilyakaznacheev/cleanenv"
+
+	"github.com/grinderz/grgo/logging"
+)
+
+type config struct {
+	TempDir string         `yaml:"tempDir" env:"CPIOPATCH_TEMP_DIR"`
+	Logging logging.Config `yaml:"logging" env-prefix:"CPIOPATCH_LOG_"`
+}
+
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+
+	if len(path) > 0 {
+		if err := cleanenv.ReadConfig(path, cfg); err != nil {
+			return nil, fmt.Errorf("read config failed: %w", err)
+		}
+	} else if err := cleanenv.ReadEnv(cfg); err != nil {
+		return nil, fmt.Errorf("read env failed: %w", err)
+	}
+
+	if len(cfg.TempDir) == 0 {
+		cfg.TempDir = os.TempDir()
+	}
+
+	return cfg, nil
+}
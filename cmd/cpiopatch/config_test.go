@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grinderz/grgo/logging"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("tempDir: /var/tmp/cpiopatch\nlogging:\n  preset: development\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.TempDir != "/var/tmp/cpiopatch" || cfg.Logging.Preset != logging.PresetDevelopment {
+		t.Fatalf("config non valid: %+v", cfg)
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	t.Setenv("CPIOPATCH_TEMP_DIR", "/var/tmp/env")
+
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.TempDir != "/var/tmp/env" {
+		t.Fatalf("temp dir non valid: %s", cfg.TempDir)
+	}
+
+	t.Setenv("CPIOPATCH_TEMP_DIR", "")
+
+	if cfg, err = loadConfig(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.TempDir != os.TempDir() {
+		t.Fatalf("default temp dir non valid: %s", cfg.TempDir)
+	}
+}
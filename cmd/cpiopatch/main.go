@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/info"
+	"github.com/grinderz/grgo/logging"
+)
+
+const (
+	appID = "cpiopatch"
+
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+var errUsage = errors.New("usage error")
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, app *app, args []string) error
+}
+
+type app struct {
+	cfg    *config
+	logger *zap.Logger
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func commands() []command {
+	return []command{
+		{"inspect", "inspect <image>...", runInspect},
+		{"list", "list <image>", runList},
+		{"extract", "extract -member <name> [-output <file>] <image>", runExtract},
+		{"patch", "patch -patterns <file> [-kernel <version>] [-backup] [-backup-dir <dir>] [-backup-keep <n>] [-workers <n>] [-mode <mode>] [-json] [-stream] [-preserve-streams] [-padding <segment>=<policy>] [-keep-temp] [-max-size <bytes>] [-max-unpack-size <bytes>] [-max-ratio <n>] [-expect-hash <digest>] [-signature <policy>] [-sign-key <file>] [-image-sign-key <file>] [-image-verify-key <file>] <image>...", runPatch},
+		{"verify", "verify -patterns <file> [-kernel <version>] <image>...", runVerify},
+		{"unpatch", "unpatch -patterns <file> [-kernel <version>] [-backup] [-workers <n>] [-mode <mode>] <image>...", runUnpatch},
+		{"repack", "repack [-backup] <image>...", runRepack},
+	}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(appID, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { usage(flags) }
+
+	configPath := flags.String("config", "", "yaml config `file`, environment variables are used when empty")
+	version := flags.Bool("version", false, "print build info and exit")
+
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if *version {
+		return printVersion(stdout)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	logger, err := logging.New(appID, &cfg.Logging)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	defer logger.Sync() //nolint:errcheck
+
+	app := &app{cfg: cfg, logger: logger, stdout: stdout, stderr: stderr}
+
+	for _, cmd := range commands() {
+		if cmd.name != flags.Arg(0) {
+			continue
+		}
+
+		if err := cmd.run(ctx, app, flags.Args()[1:]); err != nil {
+			if errors.Is(err, errUsage) {
+				fmt.Fprintf(stderr, "usage: %s %s\n", appID, cmd.usage)
+				return exitUsage
+			}
+
+			fmt.Fprintln(stderr, err)
+
+			return exitError
+		}
+
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n", flags.Arg(0))
+	flags.Usage()
+
+	return exitUsage
+}
+
+func usage(flags *flag.FlagSet) {
+	out := flags.Output()
+
+	fmt.Fprintf(out, "usage: %s [flags] <command> [command flags]\n\ncommands:\n", appID)
+
+	for _, cmd := range commands() {
+		fmt.Fprintf(out, "  %s\n", cmd.usage)
+	}
+
+	fmt.Fprintln(out, "\nflags:")
+	flags.PrintDefaults()
+}
+
+func printVersion(stdout io.Writer) int {
+	data, err := json.MarshalIndent(info.GetInstance(), "", "  ")
+	if err != nil {
+		fmt.Fprintln(stdout, info.GetInstance())
+		return exitError
+	}
+
+	fmt.Fprintln(stdout, string(data))
+
+	return exitOK
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testImage = "../../patcher/cpiopatcher/testdata/initrd.img"
+
+// newTestDir returns a directory holding a copy of the test image, a config
+// keeping the logs quiet and the path of the copied image.
+func newTestDir(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	data, err := os.ReadFile(testImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image := filepath.Join(dir, "initrd.img")
+	if err := os.WriteFile(image, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := "tempDir: " + dir + "\nlogging:\n  production:\n    level: error\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, image
+}
+
+func runTest(t *testing.T, dir string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+
+	args = append([]string{"-config", filepath.Join(dir, "config.yaml")}, args...)
+	code := run(context.Background(), args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	dir, image := newTestDir(t)
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{name: "no command", args: nil, code: exitUsage, stderr: "commands:"},
+		{name: "unknown command", args: []string{"frobnicate"}, code: exitUsage, stderr: `unknown command "frobnicate"`},
+		{name: "unknown flag", args: []string{"list", "-frobnicate", image}, code: exitUsage, stderr: "usage: cpiopatch list"},
+		{name: "missing image", args: []string{"list"}, code: exitUsage, stderr: "usage: cpiopatch list"},
+		{name: "missing member", args: []string{"extract", image}, code: exitUsage, stderr: "usage: cpiopatch extract"},
+		{name: "missing patterns", args: []string{"patch", image}, code: exitUsage, stderr: "usage: cpiopatch patch"},
+		{name: "not found", args: []string{"list", filepath.Join(dir, "missing.img")}, code: exitError},
+		{name: "list", args: []string{"list", image}, code: exitOK},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, _, stderr := runTest(t, dir, test.args...)
+			if code != test.code {
+				t.Fatalf("exit code non valid: %d, stderr: %s", code, stderr)
+			}
+
+			if !strings.Contains(stderr, test.stderr) {
+				t.Fatalf("stderr non valid: %s", stderr)
+			}
+		})
+	}
+}
+
+func TestRunGlobalFlags(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+
+	if code := run(context.Background(), []string{"-version"}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code non valid: %d", code)
+	}
+
+	if !json.Valid(stdout.Bytes()) {
+		t.Fatalf("version non valid: %s", stdout.String())
+	}
+
+	stdout.Reset()
+
+	code := run(context.Background(), []string{"-config", filepath.Join(t.TempDir(), "missing.yaml"), "list", "x"}, &stdout, &stderr)
+	if code != exitError || !strings.Contains(stderr.String(), "read config failed") {
+		t.Fatalf("missing config non valid: %d %s", code, stderr.String())
+	}
+
+	if code := run(context.Background(), []string{"-frobnicate"}, &stdout, &stderr); code != exitUsage {
+		t.Fatalf("unknown flag exit code non valid: %d", code)
+	}
+}
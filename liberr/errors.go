@@ -0,0 +1,118 @@
+package liberr
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Error classes. Errors of the patcher stack match exactly one of them with
+// errors.Is, foreign errors are classified by the Is helpers.
+var (
+	ErrUnsupported = errors.New("unsupported format")
+	ErrCorrupt     = errors.New("corrupt input")
+	ErrInvalid     = errors.New("invalid argument")
+	ErrIO          = errors.New("i/o failure")
+	ErrLimit       = errors.New("limit exceeded")
+)
+
+// Error tags Err with a Class, errors.Is and errors.As see both.
+type Error struct {
+	Class error
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Err, e.Class}
+}
+
+// New returns a sentinel error of class.
+func New(class error, text string) error {
+	return &Error{Class: class, Err: errors.New(text)}
+}
+
+// Wrap tags err with class, nil stays nil.
+func Wrap(class, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Class: class, Err: err}
+}
+
+// Corrupt tags a parser or decoder error as corrupt input unless it is
+// already classified, an operating system failure or a context error.
+func Corrupt(err error) error {
+	if err == nil || classified(err) || isOSError(err) || isContextError(err) {
+		return err
+	}
+
+	return Wrap(ErrCorrupt, err)
+}
+
+// IsRetryable reports whether repeating the operation may succeed: I/O
+// class errors, interrupted, busy or would block system calls and timeouts.
+// Other operating system failures such as a full, read only or forbidden
+// filesystem are not.
+func IsRetryable(err error) bool {
+	if err == nil || IsUserError(err) {
+		return false
+	}
+
+	var timeout interface{ Timeout() bool }
+
+	return errors.Is(err, ErrIO) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EBUSY) ||
+		errors.As(err, &timeout) && timeout.Timeout()
+}
+
+// IsUserError reports whether the caller has to fix its input: patterns,
+// options, paths or the image format.
+func IsUserError(err error) bool {
+	return errors.Is(err, ErrInvalid) ||
+		errors.Is(err, ErrUnsupported) ||
+		errors.Is(err, fs.ErrNotExist) ||
+		errors.Is(err, fs.ErrPermission)
+}
+
+// IsCorruptInput reports whether the image itself is damaged or hostile.
+func IsCorruptInput(err error) bool {
+	return errors.Is(err, ErrCorrupt) || errors.Is(err, ErrLimit)
+}
+
+func classified(err error) bool {
+	for _, class := range []error{ErrUnsupported, ErrCorrupt, ErrInvalid, ErrIO, ErrLimit} {
+		if errors.Is(err, class) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isOSError(err error) bool {
+	var (
+		pathErr    *fs.PathError
+		linkErr    *os.LinkError
+		syscallErr *os.SyscallError
+		errno      syscall.Errno
+	)
+
+	return errors.As(err, &pathErr) ||
+		errors.As(err, &linkErr) ||
+		errors.As(err, &syscallErr) ||
+		errors.As(err, &errno)
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,70 @@
+package liberr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	sentinel := liberr.New(liberr.ErrInvalid, "sentinel")
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+		user      bool
+		corrupt   bool
+	}{
+		{"nil", nil, false, false, false},
+		{"sentinel", fmt.Errorf("wrapped: %w", sentinel), false, true, false},
+		{"unsupported", liberr.Wrap(liberr.ErrUnsupported, io.EOF), false, true, false},
+		{"corrupt", liberr.Corrupt(io.ErrUnexpectedEOF), false, false, true},
+		{"limit", liberr.New(liberr.ErrLimit, "limit"), false, false, true},
+		{"io class", liberr.New(liberr.ErrIO, "io"), true, false, false},
+		{"eio", &fs.PathError{Op: "read", Path: "x", Err: syscall.EIO}, false, false, false},
+		{"corrupt eagain", liberr.Corrupt(&fs.PathError{Op: "read", Path: "x", Err: syscall.EAGAIN}), true, false, false},
+		{"eintr", &os.SyscallError{Syscall: "read", Err: syscall.EINTR}, true, false, false},
+		{"ebusy", &fs.PathError{Op: "rename", Path: "x", Err: syscall.EBUSY}, true, false, false},
+		{"enospc", &fs.PathError{Op: "write", Path: "x", Err: syscall.ENOSPC}, false, false, false},
+		{"erofs", &fs.PathError{Op: "open", Path: "x", Err: syscall.EROFS}, false, false, false},
+		{"eacces", &fs.PathError{Op: "open", Path: "x", Err: syscall.EACCES}, false, true, false},
+		{"timeout", &fs.PathError{Op: "read", Path: "x", Err: os.ErrDeadlineExceeded}, true, false, false},
+		{"not exist", &fs.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}, false, true, false},
+		{"deadline", context.DeadlineExceeded, true, false, false},
+		{"canceled", liberr.Corrupt(context.Canceled), false, false, false},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := liberr.IsRetryable(test.err); got != test.retryable {
+				t.Fatalf("retryable non valid: %v != %v", got, test.retryable)
+			}
+
+			if got := liberr.IsUserError(test.err); got != test.user {
+				t.Fatalf("user error non valid: %v != %v", got, test.user)
+			}
+
+			if got := liberr.IsCorruptInput(test.err); got != test.corrupt {
+				t.Fatalf("corrupt input non valid: %v != %v", got, test.corrupt)
+			}
+		})
+	}
+
+	if !errors.Is(fmt.Errorf("wrapped: %w", sentinel), sentinel) {
+		t.Fatal("sentinel identity lost")
+	}
+}
@@ -0,0 +1,90 @@
+package libfs
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+type File interface {
+	libio.File
+	Truncate(size int64) error
+	Sync() error
+}
+
+// FS is the writable subset of a filesystem needed to patch files, it lets
+// callers keep images and temp data outside the OS filesystem.
+type FS interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Remove(name string) error
+	// ReadDir returns the sorted names of the files in the named directory.
+	ReadDir(name string) ([]string, error)
+	// MkdirTemp creates a new directory in dir named by pattern like
+	// os.MkdirTemp and returns its path.
+	MkdirTemp(dir, pattern string) (string, error)
+	RemoveAll(path string) error
+	Stat(name string) (os.FileInfo, error)
+	// Rename replaces newpath with oldpath like os.Rename.
+	Rename(oldpath, newpath string) error
+	Chmod(name string, mode os.FileMode) error
+	Chown(name string, uid, gid int) error
+	// EvalSymlinks returns name after following its symlinks.
+	EvalSymlinks(name string) (string, error)
+	// SyncDir commits the entries of the named directory to stable storage,
+	// so a rename into it survives a crash.
+	SyncDir(name string) error
+}
+
+const createTempTries = 10000
+
+func Create(fsys FS, name string, perm os.FileMode) (File, error) {
+	return fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+// CreateTemp creates a new file in dir like os.CreateTemp and returns it with
+// its path, the last "*" of pattern is replaced by a random string.
+func CreateTemp(fsys FS, dir, pattern string) (File, string, error) {
+	prefix, suffix := pattern, ""
+	if pos := strings.LastIndexByte(pattern, '*'); pos >= 0 {
+		prefix, suffix = pattern[:pos], pattern[pos+1:]
+	}
+
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix) //nolint:gosec
+
+		file, err := fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if os.IsExist(err) && try < createTempTries {
+			continue
+		}
+
+		if err != nil {
+			return nil, "", err //nolint:wrapcheck
+		}
+
+		return file, name, nil
+	}
+}
+
+func CloneReader(fsys FS, reader io.Reader, dst string, perm os.FileMode) error {
+	dstFile, err := Create(fsys, dst, perm)
+	if err != nil {
+		return fmt.Errorf("clone reader create dst failed: %w", err)
+	}
+	defer dstFile.Close()
+
+	if _, err := io.Copy(dstFile, reader); err != nil {
+		return fmt.Errorf("clone reader copy failed: %w", err)
+	}
+
+	if err = dstFile.Sync(); err != nil {
+		return fmt.Errorf("clone reader sync dst failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,366 @@
+package libfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+// memFilePerm is the mode of files written without one.
+const memFilePerm = 0o644
+
+// MemFS keeps files in memory, names are cleaned and directories are implicit.
+// It has no symlinks and no owners. Like os files, a single handle must not
+// be used from several goroutines.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string]*libio.Buffer
+	modes map[string]os.FileMode
+	dirs  map[string]struct{}
+}
+
+func NewMemFS() *MemFS {
+	return &MemFS{
+		files: make(map[string]*libio.Buffer),
+		modes: make(map[string]os.FileMode),
+		dirs:  make(map[string]struct{}),
+	}
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+
+	data, ok := m.files[name]
+
+	switch {
+	case ok && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		m.modes[name] = perm.Perm()
+
+		fallthrough
+	case flag&os.O_TRUNC != 0:
+		data = libio.NewBuffer(nil)
+		m.files[name] = data
+	}
+
+	file := &memFile{data: data}
+
+	if flag&os.O_APPEND != 0 {
+		file.pos = int64(data.Len())
+	}
+
+	return file, nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+
+	delete(m.files, name)
+	delete(m.modes, name)
+
+	return nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return memFileInfo{name: path.Base(name), size: int64(data.Len()), mode: m.mode(name)}, nil
+}
+
+// Rename moves the file oldpath to newpath, replacing the file newpath held.
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldpath, newpath = path.Clean(oldpath), path.Clean(newpath)
+
+	data, ok := m.files[oldpath]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: fs.ErrNotExist}
+	}
+
+	m.files[newpath] = data
+	m.modes[newpath] = m.mode(oldpath)
+
+	delete(m.files, oldpath)
+	delete(m.modes, oldpath)
+
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+
+	m.modes[name] = mode.Perm()
+
+	return nil
+}
+
+// Chown only checks that the file exists, MemFS has no owners.
+func (m *MemFS) Chown(name string, _, _ int) error {
+	_, err := m.Stat(name)
+
+	return err
+}
+
+// EvalSymlinks returns the cleaned name, MemFS has no symlinks.
+func (m *MemFS) EvalSymlinks(name string) (string, error) {
+	if _, err := m.Stat(name); err != nil {
+		return "", err
+	}
+
+	return path.Clean(name), nil
+}
+
+func (*MemFS) SyncDir(string) error {
+	return nil
+}
+
+// ReadFile returns a copy of the named file content.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return append([]byte(nil), data.Bytes()...), nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[path.Clean(name)] = libio.NewBuffer(append([]byte(nil), data...))
+	m.modes[path.Clean(name)] = memFilePerm
+}
+
+func (m *MemFS) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// ReadDir lists the files whose parent is name. Directories are implicit, so
+// a missing directory is empty.
+func (m *MemFS) ReadDir(name string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+	names := make([]string, 0)
+
+	for file := range m.files {
+		if path.Dir(file) == name {
+			names = append(names, path.Base(file))
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// MkdirTemp reserves a directory name which no file or other temp directory uses.
+func (m *MemFS) MkdirTemp(dir, pattern string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+
+	for seq := len(m.dirs); ; seq++ {
+		name := path.Join(dir, prefix+strconv.Itoa(seq)+suffix)
+		if _, ok := m.dirs[name]; ok || m.used(name) {
+			continue
+		}
+
+		m.dirs[name] = struct{}{}
+
+		return name, nil
+	}
+}
+
+// RemoveAll removes the named file or directory with the files it contains.
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = path.Clean(name)
+
+	for dir := range m.dirs {
+		if inDir(dir, name) {
+			delete(m.dirs, dir)
+		}
+	}
+
+	for file := range m.files {
+		if inDir(file, name) {
+			delete(m.files, file)
+			delete(m.modes, file)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemFS) mode(name string) os.FileMode {
+	if mode, ok := m.modes[name]; ok {
+		return mode
+	}
+
+	return memFilePerm
+}
+
+func (m *MemFS) used(name string) bool {
+	for file := range m.files {
+		if inDir(file, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inDir reports whether name is dir or lies below it.
+func inDir(name, dir string) bool {
+	return name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// memFile is a handle with its own position on shared file data.
+type memFile struct {
+	data *libio.Buffer
+	pos  int64
+}
+
+func (f *memFile) Read(buff []byte) (int, error) {
+	readBytes, err := f.data.ReadAt(buff, f.pos)
+	f.pos += int64(readBytes)
+
+	if err == io.EOF && readBytes > 0 {
+		return readBytes, nil
+	}
+
+	return readBytes, err //nolint:wrapcheck
+}
+
+func (f *memFile) ReadAt(buff []byte, offset int64) (int, error) {
+	return f.data.ReadAt(buff, offset) //nolint:wrapcheck
+}
+
+func (f *memFile) Write(buff []byte) (int, error) {
+	written, err := f.data.WriteAt(buff, f.pos)
+	f.pos += int64(written)
+
+	return written, err //nolint:wrapcheck
+}
+
+func (f *memFile) WriteAt(buff []byte, offset int64) (int, error) {
+	return f.data.WriteAt(buff, offset) //nolint:wrapcheck
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += int64(f.data.Len())
+	}
+
+	if offset < 0 {
+		return 0, libio.ErrBufferNegativeOffset
+	}
+
+	f.pos = offset
+
+	return offset, nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	return f.data.Truncate(size) //nolint:wrapcheck
+}
+
+func (*memFile) Sync() error {
+	return nil
+}
+
+func (*memFile) Close() error {
+	return nil
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+func (i memFileInfo) Name() string {
+	return i.name
+}
+
+func (i memFileInfo) Size() int64 {
+	return i.size
+}
+
+func (i memFileInfo) Mode() os.FileMode {
+	return i.mode
+}
+
+func (memFileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (memFileInfo) IsDir() bool {
+	return false
+}
+
+func (memFileInfo) Sys() any {
+	return nil
+}
+
+var _ FS = &MemFS{}
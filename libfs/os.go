@@ -0,0 +1,85 @@
+package libfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type OSFS struct{}
+
+func NewOSFS() *OSFS {
+	return &OSFS{}
+}
+
+func (*OSFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	file, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return file, nil
+}
+
+func (*OSFS) Remove(name string) error {
+	return os.Remove(name) //nolint:wrapcheck
+}
+
+func (*OSFS) ReadDir(name string) ([]string, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names, nil
+}
+
+func (*OSFS) MkdirTemp(dir, pattern string) (string, error) {
+	return os.MkdirTemp(dir, pattern) //nolint:wrapcheck
+}
+
+func (*OSFS) RemoveAll(path string) error {
+	return os.RemoveAll(path) //nolint:wrapcheck
+}
+
+func (*OSFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name) //nolint:wrapcheck
+}
+
+func (*OSFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath) //nolint:wrapcheck
+}
+
+func (*OSFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode) //nolint:wrapcheck
+}
+
+func (*OSFS) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid) //nolint:wrapcheck
+}
+
+func (*OSFS) EvalSymlinks(name string) (string, error) {
+	return filepath.EvalSymlinks(name) //nolint:wrapcheck
+}
+
+func (*OSFS) SyncDir(name string) error {
+	dir, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("open dir failed: %w", err)
+	}
+
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("sync dir failed: %w", err)
+	}
+
+	return nil
+}
+
+var _ FS = &OSFS{}
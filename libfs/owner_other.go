@@ -0,0 +1,10 @@
+//go:build !unix
+
+package libfs
+
+import "os"
+
+// Owner reports no owner on platforms without unix file ownership.
+func Owner(os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
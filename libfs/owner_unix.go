@@ -0,0 +1,19 @@
+//go:build unix
+
+package libfs
+
+import (
+	"os"
+	"syscall"
+)
+
+// Owner returns the user and group ids owning the file described by info,
+// ok is false when the filesystem does not report them.
+func Owner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(stat.Uid), int(stat.Gid), true
+}
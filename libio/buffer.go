@@ -0,0 +1,106 @@
+package libio
+
+import (
+	"io"
+)
+
+// Buffer is an in-memory File, writes past the end grow it with zeros.
+type Buffer struct {
+	data []byte
+	pos  int64
+}
+
+func NewBuffer(data []byte) *Buffer {
+	return &Buffer{data: data}
+}
+
+func (b *Buffer) Read(buff []byte) (int, error) {
+	readBytes, err := b.ReadAt(buff, b.pos)
+	b.pos += int64(readBytes)
+
+	if err == io.EOF && readBytes > 0 {
+		return readBytes, nil
+	}
+
+	return readBytes, err
+}
+
+func (b *Buffer) ReadAt(buff []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, ErrBufferNegativeOffset
+	}
+
+	if offset >= int64(len(b.data)) {
+		return 0, io.EOF
+	}
+
+	readBytes := copy(buff, b.data[offset:])
+	if readBytes < len(buff) {
+		return readBytes, io.EOF
+	}
+
+	return readBytes, nil
+}
+
+func (b *Buffer) Write(buff []byte) (int, error) {
+	written, err := b.WriteAt(buff, b.pos)
+	b.pos += int64(written)
+
+	return written, err
+}
+
+func (b *Buffer) WriteAt(buff []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, ErrBufferNegativeOffset
+	}
+
+	if end := offset + int64(len(buff)); end > int64(len(b.data)) {
+		b.data = append(b.data, make([]byte, end-int64(len(b.data)))...)
+	}
+
+	return copy(b.data[offset:], buff), nil
+}
+
+func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += b.pos
+	case io.SeekEnd:
+		offset += int64(len(b.data))
+	}
+
+	if offset < 0 {
+		return 0, ErrBufferNegativeOffset
+	}
+
+	b.pos = offset
+
+	return offset, nil
+}
+
+func (b *Buffer) Truncate(size int64) error {
+	if size < 0 {
+		return ErrBufferNegativeOffset
+	}
+
+	if size <= int64(len(b.data)) {
+		b.data = b.data[:size]
+		return nil
+	}
+
+	b.data = append(b.data, make([]byte, size-int64(len(b.data)))...)
+
+	return nil
+}
+
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+func (b *Buffer) Len() int {
+	return len(b.data)
+}
+
+func (b *Buffer) Close() error {
+	return nil
+}
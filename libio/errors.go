@@ -1,7 +1,13 @@
 package libio
 
-import "errors"
+import "github.com/grinderz/grgo/liberr"
 
 var (
-	ErrUnpackMaxDecompressLimitReached = errors.New("unpack max decompress limit reached")
+	ErrUnpackMaxDecompressLimitReached = liberr.New(liberr.ErrLimit, "unpack max decompress limit reached")
+	ErrUnpackRatioLimitReached         = liberr.New(liberr.ErrLimit, "unpack max compression ratio reached")
+	ErrUnpackDictLimitReached          = liberr.New(liberr.ErrLimit, "unpack max xz dictionary size reached")
+	ErrBufferNegativeOffset            = liberr.New(liberr.ErrInvalid, "buffer negative offset")
+	ErrGZTrailer                       = liberr.New(liberr.ErrCorrupt, "gz trailer truncated")
+	ErrXZIndex                         = liberr.New(liberr.ErrCorrupt, "xz index corrupt")
+	ErrGZTrailingData                  = liberr.New(liberr.ErrCorrupt, "gz trailing data after zero padding")
 )
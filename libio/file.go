@@ -0,0 +1,33 @@
+package libio
+
+import (
+	"fmt"
+	"io"
+)
+
+// File is the random access subset of *os.File used for temp storage.
+type File interface {
+	io.ReadWriteSeeker
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+// Size returns the size of seeker without moving its current position.
+func Size(seeker io.Seeker) (int64, error) {
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("size seek current failed: %w", err)
+	}
+
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("size seek end failed: %w", err)
+	}
+
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("size seek restore failed: %w", err)
+	}
+
+	return end, nil
+}
@@ -2,11 +2,15 @@
 
 import (
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 
+	ulikunitzxz "github.com/ulikunitz/xz"
 	"github.com/xi2/xz"
+
+	"github.com/grinderz/grgo/liberr"
 )
 
 func CloneReader(reader io.Reader, dst string) error {
@@ -27,46 +31,118 @@ func CloneReader(reader io.Reader, dst string) error {
 	return nil
 }
 
-func UnpackXZ(dst io.Writer, reader io.Reader) error {
-	xzReader, err := xz.NewReader(reader, 0)
+// UnpackXZ decompresses every xz stream of reader into dst, XZStreams
+// reports their layout.
+func UnpackXZ(dst io.Writer, reader io.Reader, limits UnpackLimits) error {
+	compressed := newCountingReader(reader)
+
+	xzReader, err := xz.NewReader(compressed, limits.MaxDictSize)
 	if err != nil {
-		return fmt.Errorf("unpack xz reader failed: %w", err)
+		return fmt.Errorf("unpack xz reader failed: %w", xzError(err))
 	}
 
-	if _, err = io.Copy(dst, xzReader); err != nil {
-		return fmt.Errorf("unpack xz copy failed: %w", err)
+	if _, err := io.Copy(dst, limits.reader(xzReader, compressed)); err != nil {
+		return fmt.Errorf("unpack xz copy failed: %w", xzError(err))
 	}
 
 	return nil
 }
 
-func UnpackGZ(dst io.Writer, reader io.Reader, maxDecompressBytes int64) error {
-	gzReader, err := gzip.NewReader(reader)
-	if err != nil {
-		return fmt.Errorf("unpack gz reader failed: %w", err)
+func xzError(err error) error {
+	if errors.Is(err, xz.ErrMemlimit) {
+		return ErrUnpackDictLimitReached
 	}
 
-	defer gzReader.Close()
+	return liberr.Corrupt(err)
+}
+
+// UnpackGZ decompresses every member of the gzip data of reader into dst
+// and returns them in order. Zero bytes following the last member are
+// counted as its padding.
+func UnpackGZ(dst io.Writer, reader io.Reader, limits UnpackLimits) ([]Stream, error) {
+	compressed := newCountingReader(reader)
 
-	written, err := io.CopyN(dst, gzReader, maxDecompressBytes)
+	gzReader, err := gzip.NewReader(compressed)
 	if err != nil {
-		return fmt.Errorf("unpack gz copy failed: %w", err)
+		return nil, fmt.Errorf("unpack gz reader failed: %w", liberr.Corrupt(err))
 	}
 
-	if written == maxDecompressBytes {
-		return ErrUnpackMaxDecompressLimitReached
-	}
+	defer gzReader.Close()
 
-	return nil
+	var (
+		streams []Stream
+		offset  int64
+		src     = limits.reader(gzReader, compressed)
+	)
+
+	for {
+		gzReader.Multistream(false)
+
+		written, err := io.Copy(dst, src)
+		if err != nil {
+			return nil, fmt.Errorf("unpack gz copy failed: %w", liberr.Corrupt(err))
+		}
+
+		streams = append(streams, Stream{Offset: offset, Size: compressed.count - offset, RawSize: written})
+		offset = compressed.count
+
+		padding, err := compressed.zeros()
+		if err != nil {
+			return nil, fmt.Errorf("unpack gz padding failed: %w", err)
+		}
+
+		if padding > 0 {
+			streams[len(streams)-1].Padding = padding
+
+			return streams, nil
+		}
+
+		if err := gzReader.Reset(compressed); err != nil {
+			if errors.Is(err, io.EOF) {
+				return streams, nil
+			}
+
+			return nil, fmt.Errorf("unpack gz reader failed: %w", liberr.Corrupt(err))
+		}
+	}
 }
 
+// PackGZ compresses reader into a single gzip member. Closing the writer
+// flushes the last deflate block and writes the trailer, its error fails
+// the pack.
 func PackGZ(dst io.Writer, reader io.Reader) error {
 	gzWriter := gzip.NewWriter(dst)
-	defer gzWriter.Close()
 
 	if _, err := io.Copy(gzWriter, reader); err != nil {
+		gzWriter.Close()
+
 		return fmt.Errorf("pack gz copy failed: %w", err)
 	}
 
+	if err := gzWriter.Close(); err != nil {
+		return fmt.Errorf("pack gz close failed: %w", err)
+	}
+
+	return nil
+}
+
+// PackXZ compresses reader with CRC32 checks, the only check type the kernel
+// xz decoder is guaranteed to support.
+func PackXZ(dst io.Writer, reader io.Reader) error {
+	xzWriter, err := ulikunitzxz.WriterConfig{CheckSum: ulikunitzxz.CRC32}.NewWriter(dst)
+	if err != nil {
+		return fmt.Errorf("pack xz writer failed: %w", err)
+	}
+
+	if _, err := io.Copy(xzWriter, reader); err != nil {
+		xzWriter.Close()
+
+		return fmt.Errorf("pack xz copy failed: %w", err)
+	}
+
+	if err := xzWriter.Close(); err != nil {
+		return fmt.Errorf("pack xz close failed: %w", err)
+	}
+
 	return nil
 }
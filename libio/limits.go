@@ -0,0 +1,153 @@
+package libio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// UnpackLimits bounds what a decompressor may produce, zero fields are
+// unlimited. MaxRatio is checked against the compressed bytes read so far
+// once MinRatioSize bytes were decompressed, so small highly compressible
+// headers do not trip it. MaxDictSize only applies to xz, zero selects the
+// decoder default of 64 MiB.
+type UnpackLimits struct {
+	MaxSize     int64
+	MaxRatio    int64
+	MaxDictSize uint32
+}
+
+const (
+	// DefaultMaxUnpackSize is the decompressed size limit used by the patchers.
+	DefaultMaxUnpackSize = 500 << 20
+	// MinRatioSize is the decompressed size below which MaxRatio is not checked.
+	MinRatioSize = 1 << 20
+
+	zerosBufferSize = 4096
+)
+
+// reader applies the limits to the decompressed src, compressed counts the
+// bytes consumed from the compressed input. The limits hold across every
+// read of the returned reader, so they span all streams of a multi-stream
+// input.
+func (l UnpackLimits) reader(src io.Reader, compressed *countingReader) io.Reader {
+	if l.MaxRatio > 0 {
+		src = &ratioReader{reader: src, compressed: compressed, maxRatio: l.MaxRatio}
+	}
+
+	if l.MaxSize > 0 {
+		src = NewLimitedReader(src, l.MaxSize)
+	}
+
+	return src
+}
+
+// LimitedReader reads at most N bytes from R. Unlike io.LimitedReader it
+// fails with ErrUnpackMaxDecompressLimitReached once more than N bytes are
+// available, reading exactly N bytes up to the end of R is not an error.
+type LimitedReader struct {
+	R io.Reader
+	N int64
+}
+
+func NewLimitedReader(reader io.Reader, limit int64) *LimitedReader {
+	return &LimitedReader{R: reader, N: limit}
+}
+
+func (l *LimitedReader) Read(buff []byte) (int, error) {
+	if l.N <= 0 {
+		probe := make([]byte, 1)
+
+		if _, err := io.ReadFull(l.R, probe); err != nil {
+			return 0, err //nolint:wrapcheck
+		}
+
+		return 0, ErrUnpackMaxDecompressLimitReached
+	}
+
+	if int64(len(buff)) > l.N {
+		buff = buff[:l.N]
+	}
+
+	readBytes, err := l.R.Read(buff)
+	l.N -= int64(readBytes)
+
+	return readBytes, err //nolint:wrapcheck
+}
+
+// countingReader counts the bytes read from reader. It is an io.ByteReader
+// so decompressors do not buffer past the end of a stream and count tells
+// exactly where the stream ended.
+type countingReader struct {
+	reader *bufio.Reader
+	count  int64
+}
+
+func newCountingReader(reader io.Reader) *countingReader {
+	return &countingReader{reader: bufio.NewReader(reader)}
+}
+
+func (r *countingReader) Read(buff []byte) (int, error) {
+	readBytes, err := r.reader.Read(buff)
+	r.count += int64(readBytes)
+
+	return readBytes, err //nolint:wrapcheck
+}
+
+func (r *countingReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.count++
+	}
+
+	return b, err //nolint:wrapcheck
+}
+
+// zeros consumes the rest of the input when it starts with a zero byte and
+// returns its size, the rest must only hold zero bytes.
+func (r *countingReader) zeros() (int64, error) {
+	if next, err := r.reader.Peek(1); err != nil || next[0] != 0 {
+		return 0, nil
+	}
+
+	var (
+		zeros int64
+		buff  = make([]byte, zerosBufferSize)
+	)
+
+	for {
+		readBytes, err := r.Read(buff)
+		if len(bytes.TrimLeft(buff[:readBytes], "\x00")) > 0 {
+			return 0, ErrGZTrailingData
+		}
+
+		zeros += int64(readBytes)
+
+		if errors.Is(err, io.EOF) {
+			return zeros, nil
+		}
+
+		if err != nil {
+			return 0, err //nolint:wrapcheck
+		}
+	}
+}
+
+type ratioReader struct {
+	reader     io.Reader
+	compressed *countingReader
+	maxRatio   int64
+	read       int64
+}
+
+func (r *ratioReader) Read(buff []byte) (int, error) {
+	readBytes, err := r.reader.Read(buff)
+	r.read += int64(readBytes)
+
+	if r.read > MinRatioSize && r.read/max(r.compressed.count, 1) > r.maxRatio {
+		return 0, ErrUnpackRatioLimitReached
+	}
+
+	return readBytes, err //nolint:wrapcheck
+}
@@ -0,0 +1,134 @@
+package libio_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+func TestUnpackLimits(t *testing.T) {
+	t.Parallel()
+
+	zeros := make([]byte, 8<<20)
+
+	var gzBuff, xzBuff bytes.Buffer
+
+	if err := libio.PackGZ(&gzBuff, bytes.NewReader(zeros)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := libio.PackXZ(&xzBuff, bytes.NewReader(zeros)); err != nil {
+		t.Fatal(err)
+	}
+
+	unpackers := map[string]func(limits libio.UnpackLimits) error{
+		"gz": func(limits libio.UnpackLimits) error {
+			_, err := libio.UnpackGZ(io.Discard, bytes.NewReader(gzBuff.Bytes()), limits)
+
+			return err
+		},
+		"xz": func(limits libio.UnpackLimits) error {
+			return libio.UnpackXZ(io.Discard, bytes.NewReader(xzBuff.Bytes()), limits)
+		},
+	}
+
+	tests := []struct {
+		name   string
+		limits libio.UnpackLimits
+		err    error
+	}{
+		{"unlimited", libio.UnpackLimits{}, nil},
+		{"size", libio.UnpackLimits{MaxSize: 1 << 20}, libio.ErrUnpackMaxDecompressLimitReached},
+		{"ratio", libio.UnpackLimits{MaxRatio: 100}, libio.ErrUnpackRatioLimitReached},
+		{"dict", libio.UnpackLimits{MaxDictSize: 1 << 20}, libio.ErrUnpackDictLimitReached},
+	}
+
+	for format, unpack := range unpackers {
+		for _, test := range tests {
+			if test.name == "dict" && format == "gz" {
+				continue
+			}
+
+			if err := unpack(test.limits); !errors.Is(err, test.err) {
+				t.Fatalf("%s %s: expected %v, got %v", format, test.name, test.err, err)
+			}
+		}
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("0123456789")
+
+	tests := []struct {
+		data  []byte
+		limit int64
+		err   error
+	}{
+		{data, 9, libio.ErrUnpackMaxDecompressLimitReached},
+		{data, 10, nil},
+		{data, 11, nil},
+		{nil, 0, nil},
+		{data, 0, libio.ErrUnpackMaxDecompressLimitReached},
+	}
+
+	for _, test := range tests {
+		read, err := io.ReadAll(iotest.OneByteReader(libio.NewLimitedReader(bytes.NewReader(test.data), test.limit)))
+		if !errors.Is(err, test.err) {
+			t.Fatalf("limit %d: expected %v, got %v", test.limit, test.err, err)
+		}
+
+		if err == nil && !bytes.Equal(read, test.data) {
+			t.Fatalf("limit %d: data non valid: %q", test.limit, read)
+		}
+	}
+}
+
+func TestUnpackExactLimit(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{0, 1, 32 << 10} {
+		data := bytes.Repeat([]byte{0x5a}, size)
+
+		var gzBuff, xzBuff bytes.Buffer
+
+		if err := libio.PackGZ(&gzBuff, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := libio.PackXZ(&xzBuff, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+
+		for limit, expected := range map[int64]error{
+			int64(size):     nil,
+			int64(size) + 1: nil,
+			int64(size) - 1: libio.ErrUnpackMaxDecompressLimitReached,
+		} {
+			if limit <= 0 {
+				continue
+			}
+
+			var out bytes.Buffer
+
+			limits := libio.UnpackLimits{MaxSize: limit}
+
+			if _, err := libio.UnpackGZ(&out, bytes.NewReader(gzBuff.Bytes()), limits); !errors.Is(err, expected) {
+				t.Fatalf("gz size %d limit %d: expected %v, got %v", size, limit, expected, err)
+			}
+
+			if expected == nil && !bytes.Equal(out.Bytes(), data) {
+				t.Fatalf("gz size %d limit %d: data non valid", size, limit)
+			}
+
+			if err := libio.UnpackXZ(io.Discard, bytes.NewReader(xzBuff.Bytes()), limits); !errors.Is(err, expected) {
+				t.Fatalf("xz size %d limit %d: expected %v, got %v", size, limit, expected, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !unix
+
+package libio
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Mmap falls back to reading the whole file into memory on platforms
+// without mmap support.
+type Mmap struct {
+	*Buffer
+}
+
+func OpenMmap(file *os.File) (*Mmap, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("mmap stat failed: %w", err)
+	}
+
+	data := make([]byte, stat.Size())
+	if _, err := file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("mmap read failed: %w", err)
+	}
+
+	return &Mmap{NewBuffer(data)}, nil
+}
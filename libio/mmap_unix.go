@@ -0,0 +1,70 @@
+//go:build unix
+
+package libio
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// Mmap is a read only memory mapping of a whole file.
+// The file must not be truncated while it is mapped.
+type Mmap struct {
+	data []byte
+}
+
+func OpenMmap(file *os.File) (*Mmap, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("mmap stat failed: %w", err)
+	}
+
+	if stat.Size() == 0 {
+		return &Mmap{}, nil
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(stat.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("mmap failed: %w", err)
+	}
+
+	return &Mmap{data: data}, nil
+}
+
+func (m *Mmap) ReadAt(buff []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, ErrBufferNegativeOffset
+	}
+
+	if offset >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+
+	readBytes := copy(buff, m.data[offset:])
+	if readBytes < len(buff) {
+		return readBytes, io.EOF
+	}
+
+	return readBytes, nil
+}
+
+func (m *Mmap) Len() int {
+	return len(m.data)
+}
+
+func (m *Mmap) Close() error {
+	if m.data == nil {
+		return nil
+	}
+
+	data := m.data
+	m.data = nil
+
+	if err := syscall.Munmap(data); err != nil {
+		return fmt.Errorf("munmap failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package libio
+
+import (
+	"context"
+	"io"
+)
+
+type ContextReader struct {
+	ctx    context.Context //nolint:containedctx
+	reader io.Reader
+}
+
+func NewContextReader(ctx context.Context, reader io.Reader) *ContextReader {
+	return &ContextReader{
+		ctx:    ctx,
+		reader: reader,
+	}
+}
+
+func (r *ContextReader) Read(buff []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	return r.reader.Read(buff) //nolint:wrapcheck
+}
+
+type CountingReader struct {
+	reader  io.Reader
+	total   int64
+	onCount func(int64)
+}
+
+func NewCountingReader(reader io.Reader, onCount func(int64)) *CountingReader {
+	return &CountingReader{
+		reader:  reader,
+		onCount: onCount,
+	}
+}
+
+func (r *CountingReader) Read(buff []byte) (int, error) {
+	readBytes, err := r.reader.Read(buff)
+	if readBytes > 0 {
+		r.total += int64(readBytes)
+
+		if r.onCount != nil {
+			r.onCount(r.total)
+		}
+	}
+
+	return readBytes, err //nolint:wrapcheck
+}
+
+func (r *CountingReader) Total() int64 {
+	return r.total
+}
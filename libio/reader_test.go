@@ -0,0 +1,50 @@
+package libio_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+func TestContextReader(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := libio.NewContextReader(ctx, strings.NewReader("data"))
+
+	buff := make([]byte, 2)
+	if readBytes, err := reader.Read(buff); err != nil || readBytes != 2 {
+		t.Fatalf("read non valid: %d %v", readBytes, err)
+	}
+
+	cancel()
+
+	if _, err := reader.Read(buff); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	t.Parallel()
+
+	counts := make([]int64, 0)
+	reader := libio.NewCountingReader(strings.NewReader("abcde"), func(total int64) {
+		counts = append(counts, total)
+	})
+
+	if _, err := io.Copy(io.Discard, io.LimitReader(reader, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatal(err)
+	}
+
+	if reader.Total() != 5 || len(counts) != 2 || counts[0] != 2 || counts[1] != 5 {
+		t.Fatalf("counts non valid: %d %v", reader.Total(), counts)
+	}
+}
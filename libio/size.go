@@ -0,0 +1,192 @@
+package libio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+)
+
+const (
+	gzTrailerSize = 4
+
+	xzHeaderSize     = 12
+	xzFooterSize     = 12
+	xzAlign          = 4
+	xzVarintMaxBytes = 9
+)
+
+//nolint:gochecknoglobals
+var xzFooterMagic = []byte("YZ")
+
+// GZUncompressedSize sums the uncompressed sizes of every gzip member of the
+// size bytes read from reader, the zero padding following the last member is
+// skipped. gzip does not record where a member ends, so the members are
+// walked by inflating them without keeping their output, under limits.
+func GZUncompressedSize(reader io.ReaderAt, size int64, limits UnpackLimits) (int64, error) {
+	if size < gzTrailerSize {
+		return 0, ErrGZTrailer
+	}
+
+	streams, err := UnpackGZ(io.Discard, io.NewSectionReader(reader, 0, size), limits)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, stream := range streams {
+		total += stream.RawSize
+	}
+
+	return total, nil
+}
+
+// XZUncompressedSize sums the uncompressed sizes recorded in the indexes of
+// every xz stream of the size bytes read from reader, stream padding is skipped.
+func XZUncompressedSize(reader io.ReaderAt, size int64) (int64, error) {
+	streams, err := XZStreams(reader, size)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, stream := range streams {
+		total += stream.RawSize
+	}
+
+	return total, nil
+}
+
+// XZStreams returns the streams of the size bytes of xz data read from
+// reader in file order, as recorded by their footers and indexes. The
+// padding following a stream is counted by it, padding before the first
+// stream is not allowed by the format and is reported as ErrXZIndex.
+func XZStreams(reader io.ReaderAt, size int64) ([]Stream, error) {
+	var streams []Stream
+
+	for end := size; end > 0; {
+		dataEnd, err := skipXZPadding(reader, end)
+		if err != nil {
+			return nil, err
+		}
+
+		if dataEnd == 0 {
+			return nil, ErrXZIndex
+		}
+
+		streamSize, uncompressed, err := xzStream(reader, dataEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		streams = append(streams, Stream{
+			Offset:  dataEnd - streamSize,
+			Size:    streamSize,
+			RawSize: uncompressed,
+			Padding: end - dataEnd,
+		})
+		end = dataEnd - streamSize
+	}
+
+	slices.Reverse(streams)
+
+	return streams, nil
+}
+
+func skipXZPadding(reader io.ReaderAt, end int64) (int64, error) {
+	buff := make([]byte, xzAlign)
+
+	for ; end >= xzAlign; end -= xzAlign {
+		if _, err := reader.ReadAt(buff, end-xzAlign); err != nil {
+			return 0, fmt.Errorf("read xz padding failed: %w", err)
+		}
+
+		if !bytes.Equal(buff, make([]byte, xzAlign)) {
+			return end, nil
+		}
+	}
+
+	return end, nil
+}
+
+// xzStream parses the footer and index of the stream ending at end and
+// returns the stream size with its uncompressed size.
+func xzStream(reader io.ReaderAt, end int64) (int64, int64, error) {
+	if end < xzHeaderSize+xzFooterSize {
+		return 0, 0, ErrXZIndex
+	}
+
+	footer := make([]byte, xzFooterSize)
+	if _, err := reader.ReadAt(footer, end-xzFooterSize); err != nil {
+		return 0, 0, fmt.Errorf("read xz footer failed: %w", err)
+	}
+
+	if !bytes.Equal(footer[10:], xzFooterMagic) {
+		return 0, 0, ErrXZIndex
+	}
+
+	indexSize := (int64(binary.LittleEndian.Uint32(footer[4:8])) + 1) * xzAlign
+	if indexSize > end-xzHeaderSize-xzFooterSize {
+		return 0, 0, ErrXZIndex
+	}
+
+	index := make([]byte, indexSize)
+	if _, err := reader.ReadAt(index, end-xzFooterSize-indexSize); err != nil {
+		return 0, 0, fmt.Errorf("read xz index failed: %w", err)
+	}
+
+	if index[0] != 0 {
+		return 0, 0, ErrXZIndex
+	}
+
+	rdr := bytes.NewReader(index[1:])
+
+	records, err := xzVarint(rdr)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var blocks, uncompressed int64
+
+	for ; records > 0; records-- {
+		unpadded, err := xzVarint(rdr)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		size, err := xzVarint(rdr)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		blocks += (unpadded + xzAlign - 1) &^ (xzAlign - 1)
+		uncompressed += size
+	}
+
+	streamSize := xzHeaderSize + blocks + indexSize + xzFooterSize
+	if blocks < 0 || uncompressed < 0 || streamSize > end {
+		return 0, 0, ErrXZIndex
+	}
+
+	return streamSize, uncompressed, nil
+}
+
+func xzVarint(reader io.ByteReader) (int64, error) {
+	var value uint64
+
+	for ind := 0; ind < xzVarintMaxBytes; ind++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, ErrXZIndex
+		}
+
+		value |= uint64(b&0x7f) << (ind * 7)
+
+		if b&0x80 == 0 {
+			return int64(value), nil
+		}
+	}
+
+	return 0, ErrXZIndex
+}
@@ -0,0 +1,64 @@
+package libio_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+func TestUncompressedSize(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("initramfs"), 10000)
+
+	var gzBuff, xzBuff bytes.Buffer
+
+	if err := libio.PackGZ(&gzBuff, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	for ind := 0; ind < 2; ind++ {
+		if err := libio.PackXZ(&xzBuff, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+
+		xzBuff.Write(make([]byte, 8))
+	}
+
+	size, err := libio.GZUncompressedSize(bytes.NewReader(gzBuff.Bytes()), int64(gzBuff.Len()), libio.UnpackLimits{})
+	if err != nil || size != int64(len(data)) {
+		t.Fatalf("gz size non valid: %d %v", size, err)
+	}
+
+	size, err = libio.XZUncompressedSize(bytes.NewReader(xzBuff.Bytes()), int64(xzBuff.Len()))
+	if err != nil || size != int64(2*len(data)) {
+		t.Fatalf("xz size non valid: %d %v", size, err)
+	}
+
+	var membersBuff bytes.Buffer
+
+	for ind := 0; ind < 3; ind++ {
+		if err := libio.PackGZ(&membersBuff, bytes.NewReader(data[:len(data)/(ind+1)])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	membersBuff.Write(make([]byte, 512))
+
+	size, err = libio.GZUncompressedSize(bytes.NewReader(membersBuff.Bytes()), int64(membersBuff.Len()), libio.UnpackLimits{})
+	if want := int64(len(data) + len(data)/2 + len(data)/3); err != nil || size != want {
+		t.Fatalf("padded gz members size non valid: %d %v", size, err)
+	}
+
+	limits := libio.UnpackLimits{MaxSize: int64(len(data))}
+	if _, err := libio.GZUncompressedSize(bytes.NewReader(membersBuff.Bytes()), int64(membersBuff.Len()), limits); !errors.Is(err, libio.ErrUnpackMaxDecompressLimitReached) {
+		t.Fatalf("expected decompress limit, got %v", err)
+	}
+
+	corrupt := append([]byte{}, xzBuff.Bytes()[:xzBuff.Len()-10]...)
+	if _, err := libio.XZUncompressedSize(bytes.NewReader(corrupt), int64(len(corrupt))); !errors.Is(err, libio.ErrXZIndex) {
+		t.Fatalf("expected xz index error, got %v", err)
+	}
+}
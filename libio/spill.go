@@ -0,0 +1,103 @@
+package libio
+
+import (
+	"fmt"
+	"io"
+)
+
+// SpillFile keeps its data in a Buffer until a write would grow it past limit,
+// then moves the data into the file returned by create and continues there.
+type SpillFile struct {
+	limit  int64
+	create func() (File, error)
+	buffer *Buffer
+	file   File
+}
+
+func NewSpillFile(limit int64, create func() (File, error)) *SpillFile {
+	return &SpillFile{
+		limit:  limit,
+		create: create,
+		buffer: NewBuffer(nil),
+	}
+}
+
+func (f *SpillFile) Spilled() bool {
+	return f.file != nil
+}
+
+func (f *SpillFile) current() File {
+	if f.file != nil {
+		return f.file
+	}
+
+	return f.buffer
+}
+
+func (f *SpillFile) grow(end int64) error {
+	if f.file != nil || end <= f.limit {
+		return nil
+	}
+
+	file, err := f.create()
+	if err != nil {
+		return fmt.Errorf("spill create file failed: %w", err)
+	}
+
+	if _, err := file.Write(f.buffer.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("spill write file failed: %w", err)
+	}
+
+	if _, err := file.Seek(f.buffer.pos, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("spill seek file failed: %w", err)
+	}
+
+	f.file = file
+	f.buffer = nil
+
+	return nil
+}
+
+func (f *SpillFile) Read(buff []byte) (int, error) {
+	return f.current().Read(buff) //nolint:wrapcheck
+}
+
+func (f *SpillFile) ReadAt(buff []byte, offset int64) (int, error) {
+	return f.current().ReadAt(buff, offset) //nolint:wrapcheck
+}
+
+func (f *SpillFile) Write(buff []byte) (int, error) {
+	if f.file == nil {
+		if err := f.grow(f.buffer.pos + int64(len(buff))); err != nil {
+			return 0, err
+		}
+	}
+
+	return f.current().Write(buff) //nolint:wrapcheck
+}
+
+func (f *SpillFile) WriteAt(buff []byte, offset int64) (int, error) {
+	if err := f.grow(offset + int64(len(buff))); err != nil {
+		return 0, err
+	}
+
+	return f.current().WriteAt(buff, offset) //nolint:wrapcheck
+}
+
+func (f *SpillFile) Seek(offset int64, whence int) (int64, error) {
+	return f.current().Seek(offset, whence) //nolint:wrapcheck
+}
+
+func (f *SpillFile) Sync() error {
+	if sync, ok := f.file.(interface{ Sync() error }); ok {
+		return sync.Sync() //nolint:wrapcheck
+	}
+
+	return nil
+}
+
+func (f *SpillFile) Close() error {
+	return f.current().Close() //nolint:wrapcheck
+}
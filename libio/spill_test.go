@@ -0,0 +1,64 @@
+package libio_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+func TestSpillFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "spill")
+	file := libio.NewSpillFile(8, func() (libio.File, error) {
+		return os.Create(path)
+	})
+
+	defer file.Close()
+
+	if _, err := file.Write([]byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+
+	if file.Spilled() {
+		t.Fatal("spilled before limit")
+	}
+
+	if _, err := file.WriteAt([]byte("abcdefg"), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !file.Spilled() {
+		t.Fatal("not spilled after limit")
+	}
+
+	if _, err := file.Write([]byte("X")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, []byte("12abXdefg")) {
+		t.Fatalf("data non valid: %q", data)
+	}
+
+	size, err := libio.Size(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != int64(len(data)) {
+		t.Fatalf("size non valid: %d", size)
+	}
+}
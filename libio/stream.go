@@ -0,0 +1,83 @@
+package libio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Stream is a gzip member or an xz stream of compressed data. Offset is
+// relative to the start of the data, Size counts the compressed bytes,
+// RawSize the decompressed ones and Padding the zero bytes following the
+// stream.
+type Stream struct {
+	Offset  int64 `json:"offset"`
+	Size    int64 `json:"size"`
+	RawSize int64 `json:"rawSize"`
+	Padding int64 `json:"padding,omitempty"`
+}
+
+// PackGZStreams compresses reader into one gzip member per stream, each
+// holding RawSize bytes, the last member takes whatever is left. No member
+// is added once reader is exhausted, so data that shrank yields fewer
+// members. Without streams a single member is written.
+func PackGZStreams(dst io.Writer, reader io.Reader, streams []Stream) error {
+	return packStreams(dst, reader, streams, PackGZ)
+}
+
+// PackXZStreams compresses reader into one xz stream per stream, each
+// holding RawSize bytes and followed by Padding zero bytes, the last stream
+// takes whatever is left.
+func PackXZStreams(dst io.Writer, reader io.Reader, streams []Stream) error {
+	return packStreams(dst, reader, streams, PackXZ)
+}
+
+func packStreams(dst io.Writer, reader io.Reader, streams []Stream, pack func(io.Writer, io.Reader) error) error {
+	for ind, stream := range streams {
+		if ind > 0 {
+			var (
+				ok  bool
+				err error
+			)
+
+			if reader, ok, err = peek(reader); err != nil || !ok {
+				return err
+			}
+		}
+
+		src := reader
+		if ind < len(streams)-1 {
+			src = io.LimitReader(reader, stream.RawSize)
+		}
+
+		if err := pack(dst, src); err != nil {
+			return err
+		}
+
+		if _, err := dst.Write(make([]byte, stream.Padding)); err != nil {
+			return fmt.Errorf("pack stream padding failed: %w", err)
+		}
+	}
+
+	if len(streams) == 0 {
+		return pack(dst, reader)
+	}
+
+	return nil
+}
+
+// peek returns a reader equivalent to reader, it reports false when reader
+// is exhausted.
+func peek(reader io.Reader) (io.Reader, bool, error) {
+	var first [1]byte
+	if _, err := io.ReadFull(reader, first[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("pack stream read failed: %w", err)
+	}
+
+	return io.MultiReader(bytes.NewReader(first[:]), reader), true, nil
+}
@@ -0,0 +1,160 @@
+package libio_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+func TestGZStreams(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("initramfs"), 1000)
+	layout := []libio.Stream{{RawSize: 100}, {RawSize: 0}, {RawSize: 2000}, {}}
+
+	var gzBuff bytes.Buffer
+
+	if err := libio.PackGZStreams(&gzBuff, bytes.NewReader(data), layout); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw bytes.Buffer
+
+	streams, err := libio.UnpackGZ(&raw, bytes.NewReader(gzBuff.Bytes()), libio.UnpackLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(raw.Bytes(), data) {
+		t.Fatal("gz data non valid")
+	}
+
+	rawSizes := make([]int64, 0, len(streams))
+
+	var end int64
+
+	for _, stream := range streams {
+		if stream.Offset != end {
+			t.Fatalf("gz stream offset non valid: %+v", stream)
+		}
+
+		end += stream.Size
+		rawSizes = append(rawSizes, stream.RawSize)
+	}
+
+	if expected := []int64{100, 0, 2000, int64(len(data)) - 2100}; end != int64(gzBuff.Len()) || !reflect.DeepEqual(rawSizes, expected) {
+		t.Fatalf("gz streams non valid: %+v", streams)
+	}
+
+	var repacked bytes.Buffer
+
+	if err := libio.PackGZStreams(&repacked, bytes.NewReader(data[:50]), streams); err != nil {
+		t.Fatal(err)
+	}
+
+	shrunk, err := libio.UnpackGZ(&raw, bytes.NewReader(repacked.Bytes()), libio.UnpackLimits{})
+	if err != nil || len(shrunk) != 1 {
+		t.Fatalf("shrunk gz streams non valid: %+v %v", shrunk, err)
+	}
+}
+
+func TestXZStreams(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("initramfs"), 1000)
+	layout := []libio.Stream{{RawSize: 1000, Padding: 4}, {Padding: 8}}
+
+	var xzBuff bytes.Buffer
+
+	if err := libio.PackXZStreams(&xzBuff, bytes.NewReader(data), layout); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw bytes.Buffer
+
+	if err := libio.UnpackXZ(&raw, bytes.NewReader(xzBuff.Bytes()), libio.UnpackLimits{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(raw.Bytes(), data) {
+		t.Fatal("xz data non valid")
+	}
+
+	streams, err := libio.XZStreams(bytes.NewReader(xzBuff.Bytes()), int64(xzBuff.Len()))
+	if err != nil || len(streams) != 2 {
+		t.Fatalf("xz streams non valid: %+v %v", streams, err)
+	}
+
+	first, second := streams[0], streams[1]
+	if first.Offset != 0 || first.RawSize != 1000 || first.Padding != 4 ||
+		second.Offset != first.Size+4 || second.RawSize != int64(len(data))-1000 || second.Padding != 8 ||
+		second.Offset+second.Size+8 != int64(xzBuff.Len()) {
+		t.Fatalf("xz streams non valid: %+v", streams)
+	}
+}
+
+func TestGZPadding(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("initramfs"), 1000)
+
+	var gzBuff bytes.Buffer
+
+	if err := libio.PackGZStreams(&gzBuff, bytes.NewReader(data), []libio.Stream{{Padding: 5000}}); err != nil {
+		t.Fatal(err)
+	}
+
+	streams, err := libio.UnpackGZ(io.Discard, bytes.NewReader(gzBuff.Bytes()), libio.UnpackLimits{})
+	if err != nil || len(streams) != 1 || streams[0].Padding != 5000 || streams[0].Size+5000 != int64(gzBuff.Len()) {
+		t.Fatalf("gz padding non valid: %+v %v", streams, err)
+	}
+
+	gzBuff.WriteByte(1)
+
+	if _, err := libio.UnpackGZ(io.Discard, bytes.NewReader(gzBuff.Bytes()), libio.UnpackLimits{}); !errors.Is(err, libio.ErrGZTrailingData) {
+		t.Fatalf("expected gz trailing data, got %v", err)
+	}
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// shortWriter accepts n bytes then fails every write.
+type shortWriter struct {
+	n int
+}
+
+func (w *shortWriter) Write(buff []byte) (int, error) {
+	if len(buff) > w.n {
+		return 0, errWriteFailed
+	}
+
+	w.n -= len(buff)
+
+	return len(buff), nil
+}
+
+func TestPackGZTrailerFailed(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("initramfs"), 100)
+
+	var packed bytes.Buffer
+	if err := libio.PackGZ(&packed, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pack := range []func(io.Writer, io.Reader) error{
+		libio.PackGZ,
+		func(dst io.Writer, reader io.Reader) error {
+			return libio.PackGZStreams(dst, reader, []libio.Stream{{RawSize: 10}, {}})
+		},
+	} {
+		if err := pack(&shortWriter{n: packed.Len() - 4}, bytes.NewReader(data)); !errors.Is(err, errWriteFailed) {
+			t.Fatalf("expected write failed, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package libos
+
+import "github.com/grinderz/grgo/liberr"
+
+var ErrFreeSpaceUnsupported = liberr.New(liberr.ErrUnsupported, "free space not supported on this platform")
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd)
+
+package libos
+
+// FreeSpace is not supported on this platform.
+func FreeSpace(_ string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
+
+// FileSystemID is not supported on this platform.
+func FileSystemID(_ string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin || freebsd
+
+package libos
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// FreeSpace returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func FreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("statfs %s failed: %w", path, err)
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil //nolint:unconvert
+}
+
+// FileSystemID returns the device of the filesystem holding path, paths on
+// the same filesystem share it.
+func FileSystemID(path string) (uint64, error) {
+	var stat syscall.Stat_t
+
+	if err := syscall.Stat(path, &stat); err != nil {
+		return 0, fmt.Errorf("stat %s failed: %w", path, err)
+	}
+
+	return uint64(stat.Dev), nil //nolint:unconvert
+}
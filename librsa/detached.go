@@ -0,0 +1,171 @@
+package librsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+// Signer produces detached signatures over whole files.
+type Signer struct {
+	Key    *rsa.PrivateKey
+	Hash   crypto.Hash
+	Scheme SchemeEnum
+}
+
+// Verifier checks detached signatures made by Signer or by
+// "openssl dgst -sign", PSS signatures with any salt length are accepted.
+type Verifier struct {
+	Key    *rsa.PublicKey
+	Hash   crypto.Hash
+	Scheme SchemeEnum
+}
+
+func LoadSigner(keyPath string, hash crypto.Hash, scheme SchemeEnum) (*Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key failed: %w", err)
+	}
+
+	key, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{Key: key, Hash: hash, Scheme: scheme}, nil
+}
+
+func LoadVerifier(keyPath string, hash crypto.Hash, scheme SchemeEnum) (*Verifier, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read verify key failed: %w", err)
+	}
+
+	key, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{Key: key, Hash: hash, Scheme: scheme}, nil
+}
+
+func (s *Signer) Sign(reader io.Reader) ([]byte, error) {
+	digest, err := digestReader(s.Hash, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var signature []byte
+
+	switch s.Scheme {
+	case SchemePKCS1v15:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, s.Key, s.Hash, digest)
+	case SchemePSS:
+		signature, err = rsa.SignPSS(rand.Reader, s.Key, s.Hash, digest, &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		})
+	default:
+		return nil, &SchemeValueError{Value: s.Scheme.String()}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("rsa sign failed: %w", err)
+	}
+
+	return signature, nil
+}
+
+func (v *Verifier) Verify(reader io.Reader, signature []byte) error {
+	digest, err := digestReader(v.Hash, reader)
+	if err != nil {
+		return err
+	}
+
+	switch v.Scheme {
+	case SchemePKCS1v15:
+		err = rsa.VerifyPKCS1v15(v.Key, v.Hash, digest, signature)
+	case SchemePSS:
+		err = rsa.VerifyPSS(v.Key, v.Hash, digest, signature, &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthAuto,
+		})
+	default:
+		return &SchemeValueError{Value: v.Scheme.String()}
+	}
+
+	if err != nil {
+		return fmt.Errorf("rsa verify failed: %w", liberr.Wrap(liberr.ErrCorrupt, err))
+	}
+
+	return nil
+}
+
+func digestReader(hash crypto.Hash, reader io.Reader) ([]byte, error) {
+	if !hash.Available() {
+		return nil, &HashUnavailableError{Hash: hash}
+	}
+
+	hasher := hash.New()
+
+	if _, err := io.Copy(hasher, reader); err != nil {
+		return nil, fmt.Errorf("hash copy failed: %w", err)
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+// ParsePublicKeyPEM returns the first RSA public key of data, encoded as
+// PKIX "PUBLIC KEY", PKCS#1 "RSA PUBLIC KEY" or inside a "CERTIFICATE".
+func ParsePublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var (
+			key any
+			err error
+		)
+
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("parse public key failed: %w", liberr.Wrap(liberr.ErrInvalid, err))
+		}
+
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, ErrNotRSAKey
+		}
+
+		return rsaKey, nil
+	}
+
+	return nil, ErrPublicKeyNotFound
+}
+
+type HashUnavailableError struct {
+	Hash crypto.Hash
+}
+
+func (e *HashUnavailableError) Error() string {
+	return fmt.Sprintf("hash %s not available", e.Hash)
+}
+
+func (e *HashUnavailableError) Unwrap() error {
+	return liberr.ErrInvalid
+}
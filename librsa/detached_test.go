@@ -0,0 +1,43 @@
+package librsa_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/librsa"
+)
+
+func TestDetachedSignature(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	checkError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	checkError(t, err)
+
+	pubKey, err := librsa.ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	checkError(t, err)
+
+	data := bytes.Repeat([]byte("initramfs"), 1024)
+
+	for _, scheme := range []librsa.SchemeEnum{librsa.SchemePKCS1v15, librsa.SchemePSS} {
+		signer := &librsa.Signer{Key: key, Hash: crypto.SHA256, Scheme: scheme}
+		verifier := &librsa.Verifier{Key: pubKey, Hash: crypto.SHA256, Scheme: scheme}
+
+		signature, err := signer.Sign(bytes.NewReader(data))
+		checkError(t, err)
+		checkError(t, verifier.Verify(bytes.NewReader(data), signature))
+
+		tampered := append([]byte{0}, data[1:]...)
+		if err := verifier.Verify(bytes.NewReader(tampered), signature); !liberr.IsCorruptInput(err) {
+			t.Fatalf("%s: tampered data verified: %v", scheme, err)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package librsa
+
+import "github.com/grinderz/grgo/liberr"
+
+var (
+	ErrNotRSAKey          = liberr.New(liberr.ErrInvalid, "key is not an rsa key")
+	ErrPrivateKeyNotFound = liberr.New(liberr.ErrInvalid, "pem private key not found")
+	ErrPublicKeyNotFound  = liberr.New(liberr.ErrInvalid, "pem public key not found")
+)
@@ -2,9 +2,14 @@
 
 import (
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/binary"
+	"encoding/pem"
+	"fmt"
 	"math/big"
 	"unsafe"
+
+	"github.com/grinderz/grgo/liberr"
 )
 
 func ParsePublicKey(ns, es []byte) *rsa.PublicKey {
@@ -65,3 +70,33 @@ func PublicDecrypt(pubKey *rsa.PublicKey, data []byte) []byte {
 
 	return out[skip:]
 }
+
+// ParsePrivateKeyPEM returns the first RSA private key of data, encoded as
+// PKCS#1 "RSA PRIVATE KEY" or PKCS#8 "PRIVATE KEY" block.
+func ParsePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse pkcs1 private key failed: %w", liberr.Wrap(liberr.ErrInvalid, err))
+			}
+
+			return key, nil
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse pkcs8 private key failed: %w", liberr.Wrap(liberr.ErrInvalid, err))
+			}
+
+			rsaKey, ok := key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, ErrNotRSAKey
+			}
+
+			return rsaKey, nil
+		}
+	}
+
+	return nil, ErrPrivateKeyNotFound
+}
@@ -0,0 +1,67 @@
+package librsa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=SchemeEnum -linecomment -output scheme_enum_string.go
+type SchemeEnum int
+
+const (
+	SchemeUnknown  SchemeEnum = iota // unknown
+	SchemePKCS1v15 SchemeEnum = iota // pkcs1v15
+	SchemePSS      SchemeEnum = iota // pss
+)
+
+func (e *SchemeEnum) SetValue(value string) error {
+	mode := SchemeFromString(value)
+	if mode == SchemeUnknown {
+		return &SchemeValueError{
+			Value: value,
+		}
+	}
+
+	*e = mode
+
+	return nil
+}
+
+func (e SchemeEnum) MarshalText() ([]byte, error) {
+	if e == SchemeUnknown {
+		return nil, &SchemeValueError{
+			Value: SchemeUnknown.String(),
+		}
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *SchemeEnum) UnmarshalText(text []byte) error {
+	return e.SetValue(string(text))
+}
+
+func SchemeFromString(value string) SchemeEnum {
+	switch strings.ToLower(value) {
+	case "pkcs1v15":
+		return SchemePKCS1v15
+	case "pss":
+		return SchemePSS
+	default:
+		return SchemeUnknown
+	}
+}
+
+type SchemeValueError struct {
+	Value string
+}
+
+func (e *SchemeValueError) Error() string {
+	return fmt.Sprintf("rsa signature scheme invalid value: %s", e.Value)
+}
+
+func (e *SchemeValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
@@ -0,0 +1,25 @@
+// Code generated by "stringer -type=SchemeEnum -linecomment -output scheme_enum_string.go"; DO NOT EDIT.
+
+package librsa
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SchemeUnknown-0]
+	_ = x[SchemePKCS1v15-1]
+	_ = x[SchemePSS-2]
+}
+
+const _SchemeEnum_name = "unknownpkcs1v15pss"
+
+var _SchemeEnum_index = [...]uint8{0, 7, 15, 18}
+
+func (i SchemeEnum) String() string {
+	if i < 0 || i >= SchemeEnum(len(_SchemeEnum_index)-1) {
+		return "SchemeEnum(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _SchemeEnum_name[_SchemeEnum_index[i]:_SchemeEnum_index[i+1]]
+}
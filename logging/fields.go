@@ -1,6 +1,9 @@
 package logging
 
 import (
+	"fmt"
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -8,3 +11,48 @@
 func ZapFieldPkg(pkg string) zapcore.Field {
 	return zap.String("pkg", pkg)
 }
+
+func ZapFieldPath(path string) zapcore.Field {
+	return zap.String("path", path)
+}
+
+func ZapFieldDir(dir string) zapcore.Field {
+	return zap.String("dir", dir)
+}
+
+func ZapFieldPhase(phase fmt.Stringer) zapcore.Field {
+	return zap.Stringer("phase", phase)
+}
+
+func ZapFieldPattern(index int) zapcore.Field {
+	return zap.Int("pattern", index)
+}
+
+func ZapFieldDescription(description string) zapcore.Field {
+	return zap.String("description", description)
+}
+
+func ZapFieldMember(name string) zapcore.Field {
+	return zap.String("member", name)
+}
+
+func ZapFieldOffsets(offsets []int64) zapcore.Field {
+	return zap.Int64s("offsets", offsets)
+}
+
+// ZapFieldFormat logs a compression or file format such as gz or xz.
+func ZapFieldFormat(format fmt.Stringer) zapcore.Field {
+	return zap.Stringer("format", format)
+}
+
+func ZapFieldBytes(bytes int64) zapcore.Field {
+	return zap.Int64("bytes", bytes)
+}
+
+func ZapFieldCount(count int) zapcore.Field {
+	return zap.Int("count", count)
+}
+
+func ZapFieldDuration(duration time.Duration) zapcore.Field {
+	return zap.Duration("duration", duration)
+}
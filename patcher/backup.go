@@ -0,0 +1,159 @@
+package patcher
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/libfs"
+)
+
+const (
+	backupExt        = ".bak"
+	backupGZExt      = ".gz"
+	backupTimeFormat = "20060102T150405Z"
+)
+
+// BackupPolicy selects where and how the original file is kept before it is
+// replaced. The zero policy writes <path>.bak next to the file, overwriting
+// the previous backup. Timestamped backups are named
+// <name>.<UTC time>.bak and Keep limits them to the newest ones, a non
+// positive Keep retains all of them. Compressed backups get a .gz suffix.
+type BackupPolicy struct {
+	Dir         string
+	Timestamp   bool
+	Keep        int
+	NoOverwrite bool
+	Compress    bool
+}
+
+// Path returns the backup path of path made at now.
+func (b BackupPolicy) Path(path string, now time.Time) string {
+	dir := b.Dir
+	if len(dir) == 0 {
+		dir = filepath.Dir(path)
+	}
+
+	name := filepath.Base(path)
+	if b.Timestamp {
+		name += "." + now.UTC().Format(backupTimeFormat)
+	}
+
+	name += backupExt
+	if b.Compress {
+		name += backupGZExt
+	}
+
+	return filepath.Join(dir, name)
+}
+
+// Write stores the content of reader as the backup of path and applies the
+// retention, it returns the backup path. The backup gets the permissions and
+// owner of path and replaces a previous one atomically.
+func (b BackupPolicy) Write(fsys libfs.FS, path string, reader io.Reader, now time.Time) (string, error) {
+	backupPath := b.Path(path, now)
+
+	if b.NoOverwrite {
+		if _, err := fsys.Stat(backupPath); err == nil {
+			return "", &BackupExistsError{Path: backupPath}
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("backup stat failed: %w", err)
+		}
+	}
+
+	backupFile, err := CreateWorkFileAs(fsys, backupPath, path, "")
+	if err != nil {
+		return "", fmt.Errorf("backup create failed: %w", err)
+	}
+
+	defer backupFile.Remove() //nolint:errcheck
+
+	if err := b.copy(backupFile, reader); err != nil {
+		return "", err
+	}
+
+	if err := backupFile.Commit(); err != nil {
+		return "", fmt.Errorf("backup commit failed: %w", err)
+	}
+
+	return backupPath, b.prune(fsys, path)
+}
+
+func (b BackupPolicy) copy(dst io.Writer, reader io.Reader) error {
+	if !b.Compress {
+		if _, err := io.Copy(dst, reader); err != nil {
+			return fmt.Errorf("backup copy failed: %w", err)
+		}
+
+		return nil
+	}
+
+	gzWriter := gzip.NewWriter(dst)
+
+	if _, err := io.Copy(gzWriter, reader); err != nil {
+		return fmt.Errorf("backup compress failed: %w", err)
+	}
+
+	if err := gzWriter.Close(); err != nil {
+		return fmt.Errorf("backup compress close failed: %w", err)
+	}
+
+	return nil
+}
+
+// prune removes the oldest timestamped backups of path beyond Keep. Names
+// sort by time, so compressed and plain backups are pruned together.
+func (b BackupPolicy) prune(fsys libfs.FS, path string) error {
+	if !b.Timestamp || b.Keep <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(b.Path(path, time.Time{}))
+	prefix := filepath.Base(path) + "."
+
+	names, err := fsys.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("backup dir read failed: %w", err)
+	}
+
+	backups := make([]string, 0, len(names))
+
+	for _, name := range names {
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, backupGZExt), backupExt)
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, name)
+		}
+	}
+
+	for len(backups) > b.Keep {
+		if err := fsys.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return fmt.Errorf("backup remove failed: %w", err)
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+type BackupExistsError struct {
+	Path string
+}
+
+func (e *BackupExistsError) Error() string {
+	return fmt.Sprintf("backup %s already exists", e.Path)
+}
+
+func (e *BackupExistsError) Unwrap() error {
+	return liberr.ErrInvalid
+}
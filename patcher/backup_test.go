@@ -0,0 +1,102 @@
+package patcher_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestBackupPolicy(t *testing.T) {
+	t.Parallel()
+
+	fsys := libfs.NewMemFS()
+	fsys.WriteFile("boot/initrd.img", []byte("original"))
+	fsys.WriteFile("initrd.img", []byte("original"))
+
+	if err := fsys.Chmod("boot/initrd.img", 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := patcher.BackupPolicy{Dir: "bak", Timestamp: true, Keep: 2, Compress: true}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var paths []string
+
+	for ind := 0; ind < 3; ind++ {
+		path, err := policy.Write(fsys, "boot/initrd.img", strings.NewReader("original"), now.Add(time.Duration(ind)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	if paths[0] != "bak/initrd.img.20240102T030405Z.bak.gz" {
+		t.Fatalf("backup path non valid: %s", paths[0])
+	}
+
+	names, err := fsys.ReadDir("bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 2 || "bak/"+names[0] != paths[1] {
+		t.Fatalf("retained backups non valid: %v", names)
+	}
+
+	data, err := fsys.ReadFile(paths[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plain, err := io.ReadAll(gzReader); err != nil || string(plain) != "original" {
+		t.Fatalf("backup content non valid: %q %v", plain, err)
+	}
+
+	if info, err := fsys.Stat(paths[2]); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("backup mode non valid: %v %v", info, err)
+	}
+
+	policy = patcher.BackupPolicy{NoOverwrite: true}
+
+	if _, err := policy.Write(fsys, "initrd.img", strings.NewReader("a"), now); err != nil {
+		t.Fatal(err)
+	}
+
+	var existsErr *patcher.BackupExistsError
+	if _, err := policy.Write(fsys, "initrd.img", strings.NewReader("b"), now); !errors.As(err, &existsErr) {
+		t.Fatalf("expected backup exists, got %v", err)
+	}
+
+	policy = patcher.BackupPolicy{}
+
+	if _, err := policy.Write(fsys, "initrd.img", io.MultiReader(strings.NewReader("b"), iotest.ErrReader(errReadFailed)), now); !errors.Is(err, errReadFailed) {
+		t.Fatalf("expected read failed, got %v", err)
+	}
+
+	if data, err := fsys.ReadFile("initrd.img.bak"); err != nil || string(data) != "a" {
+		t.Fatalf("previous backup non valid: %q %v", data, err)
+	}
+
+	for _, name := range fsys.Names() {
+		if strings.HasPrefix(filepath.Base(name), ".") {
+			t.Fatalf("work file left: %s", name)
+		}
+	}
+}
+
+var errReadFailed = errors.New("read failed")
@@ -0,0 +1,74 @@
+package patcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+type BatchJob func(ctx context.Context, path string) Result
+
+// RunBatch runs job for every path on at most workers goroutines and returns
+// the results in the order of paths. In fail-fast mode the first failed job
+// cancels the ones still running and the remaining paths are reported as
+// skipped.
+func RunBatch(ctx context.Context, paths []string, workers int, mode BatchModeEnum, job BatchJob) []Result {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	results := make([]Result, len(paths))
+	indexes := make(chan int)
+
+	for i := 0; i < min(workers, len(paths)); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for index := range indexes {
+				results[index] = runBatchJob(ctx, paths[index], job)
+
+				if results[index].Err != nil && mode == BatchModeFailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for index := range paths {
+		indexes <- index
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+func runBatchJob(ctx context.Context, path string, job BatchJob) Result {
+	if err := ctx.Err(); err != nil {
+		return NewError(path, fmt.Errorf("%s: %w: %w", path, ErrBatchSkipped, err))
+	}
+
+	return job(ctx, path)
+}
+
+func JoinErrors(results []Result) error {
+	errs := make([]error, 0, len(results))
+
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
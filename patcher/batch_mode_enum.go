@@ -0,0 +1,67 @@
+package patcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=BatchModeEnum -linecomment -output batch_mode_enum_string.go
+type BatchModeEnum int
+
+const (
+	BatchModeUnknown    BatchModeEnum = iota // unknown
+	BatchModeBestEffort BatchModeEnum = iota // best-effort
+	BatchModeFailFast   BatchModeEnum = iota // fail-fast
+)
+
+func (e *BatchModeEnum) SetValue(value string) error {
+	mode := BatchModeFromString(value)
+	if mode == BatchModeUnknown {
+		return &BatchModeValueError{
+			Value: value,
+		}
+	}
+
+	*e = mode
+
+	return nil
+}
+
+func (e BatchModeEnum) MarshalText() ([]byte, error) {
+	if e == BatchModeUnknown {
+		return nil, &BatchModeValueError{
+			Value: BatchModeUnknown.String(),
+		}
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *BatchModeEnum) UnmarshalText(text []byte) error {
+	return e.SetValue(string(text))
+}
+
+func BatchModeFromString(value string) BatchModeEnum {
+	switch strings.ToLower(value) {
+	case "best-effort":
+		return BatchModeBestEffort
+	case "fail-fast":
+		return BatchModeFailFast
+	default:
+		return BatchModeUnknown
+	}
+}
+
+type BatchModeValueError struct {
+	Value string
+}
+
+func (e *BatchModeValueError) Error() string {
+	return fmt.Sprintf("batch mode invalid value: %s", e.Value)
+}
+
+func (e *BatchModeValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
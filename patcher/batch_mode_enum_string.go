@@ -0,0 +1,25 @@
+// Code generated by "stringer -type=BatchModeEnum -linecomment -output batch_mode_enum_string.go"; DO NOT EDIT.
+
+package patcher
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[BatchModeUnknown-0]
+	_ = x[BatchModeBestEffort-1]
+	_ = x[BatchModeFailFast-2]
+}
+
+const _BatchModeEnum_name = "unknownbest-effortfail-fast"
+
+var _BatchModeEnum_index = [...]uint8{0, 7, 18, 27}
+
+func (i BatchModeEnum) String() string {
+	if i < 0 || i >= BatchModeEnum(len(_BatchModeEnum_index)-1) {
+		return "BatchModeEnum(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _BatchModeEnum_name[_BatchModeEnum_index[i]:_BatchModeEnum_index[i+1]]
+}
@@ -0,0 +1,87 @@
+package patcher_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/patcher"
+)
+
+var errBatchTest = errors.New("batch test")
+
+func TestRunBatchBestEffort(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{"a", "b", "c", "d", "e"}
+
+	var running, maxRunning atomic.Int32
+
+	results := patcher.RunBatch(context.Background(), paths, 2, patcher.BatchModeBestEffort,
+		func(_ context.Context, path string) patcher.Result {
+			current := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				seen := maxRunning.Load()
+				if current <= seen || maxRunning.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+
+			if path == "b" {
+				return patcher.NewError(path, errBatchTest)
+			}
+
+			return patcher.NewResult(path, len(path))
+		})
+
+	if maxRunning.Load() > 2 {
+		t.Fatalf("workers limit exceeded: %d", maxRunning.Load())
+	}
+
+	for ind, result := range results {
+		if result.Path != paths[ind] {
+			t.Fatalf("result %d path mismatch: %s != %s", ind, result.Path, paths[ind])
+		}
+
+		if (result.Err != nil) != (result.Path == "b") {
+			t.Fatalf("result %d unexpected err: %v", ind, result.Err)
+		}
+	}
+
+	if !errors.Is(patcher.JoinErrors(results), errBatchTest) {
+		t.Fatal("joined errors do not contain job error")
+	}
+}
+
+func TestRunBatchFailFast(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{"a", "b", "c", "d"}
+
+	results := patcher.RunBatch(context.Background(), paths, 1, patcher.BatchModeFailFast,
+		func(_ context.Context, path string) patcher.Result {
+			if path == "b" {
+				return patcher.NewError(path, errBatchTest)
+			}
+
+			return patcher.NewResult(path, 1)
+		})
+
+	if results[0].Err != nil {
+		t.Fatalf("first job failed: %v", results[0].Err)
+	}
+
+	if !errors.Is(results[1].Err, errBatchTest) {
+		t.Fatalf("second job unexpected err: %v", results[1].Err)
+	}
+
+	for _, result := range results[2:] {
+		if !errors.Is(result.Err, patcher.ErrBatchSkipped) || !liberr.IsRetryable(result.Err) {
+			t.Fatalf("%s: expected skipped, got %v", result.Path, result.Err)
+		}
+	}
+}
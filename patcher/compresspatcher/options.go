@@ -0,0 +1,105 @@
+package compresspatcher
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/patcher"
+)
+
+// Option configures a Patcher built by NewPatcher.
+type Option func(p *Patcher)
+
+// NewPatcher returns a patcher of the compressed file at path. Without
+// options it patches the file on the OS filesystem, bounds the payload to
+// the default unpack size, repacks it into a single gzip member or xz
+// stream, keeps backups next to the file and logs to the logger of the
+// context of every run.
+func NewPatcher(path string, opts ...Option) *Patcher {
+	p := &Patcher{
+		path:   path,
+		fs:     libfs.NewOSFS(),
+		now:    time.Now,
+		limits: libio.UnpackLimits{MaxSize: libio.DefaultMaxUnpackSize},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithResult makes Patch send its result to result.
+func WithResult(result chan<- patcher.Result) Option {
+	return func(p *Patcher) {
+		p.result = result
+	}
+}
+
+// WithLogger logs to logger, a nil logger selects the logger of the context
+// of every run.
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Patcher) {
+		p.logger = logger
+	}
+}
+
+// WithFS makes the patcher read, back up and replace the file and keep its
+// work files through fsys instead of the OS filesystem.
+func WithFS(fsys libfs.FS) Option {
+	return func(p *Patcher) {
+		p.fs = fsys
+	}
+}
+
+// WithClock uses now to timestamp backups.
+func WithClock(now func() time.Time) Option {
+	return func(p *Patcher) {
+		p.now = now
+	}
+}
+
+// WithDryRun makes the patcher search and validate every pattern without
+// touching the file, the result reports the bytes which would be patched.
+func WithDryRun(dryRun bool) Option {
+	return func(p *Patcher) {
+		p.dryRun = dryRun
+	}
+}
+
+// WithUnpackLimits bounds the decompressed size, compression ratio and xz
+// dictionary size of the payload, it defaults to a 500 MiB size limit.
+func WithUnpackLimits(limits libio.UnpackLimits) Option {
+	return func(p *Patcher) {
+		p.limits = limits
+	}
+}
+
+// WithBackupPolicy selects the location, naming and retention of backups
+// written when Patch is asked to keep one.
+func WithBackupPolicy(policy patcher.BackupPolicy) Option {
+	return func(p *Patcher) {
+		p.backupPol = policy
+	}
+}
+
+// WithPreserveStreams repacks the payload into as many gzip members or xz
+// streams as the input, holding the same uncompressed sizes and followed by
+// the same zero padding, instead of a single one.
+func WithPreserveStreams(preserve bool) Option {
+	return func(p *Patcher) {
+		p.preserve = preserve
+	}
+}
+
+// WithProgress registers a callback invoked from the patching goroutine
+// each time a phase consumes more of its input.
+func WithProgress(progress patcher.ProgressFunc) Option {
+	return func(p *Patcher) {
+		p.progress = progress
+	}
+}
@@ -0,0 +1,300 @@
+package compresspatcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/logging"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+	"github.com/grinderz/grgo/patcher/libelf"
+)
+
+const bufferSize = 8192
+
+var _ patcher.Patcher = (*Patcher)(nil)
+
+// Patcher patches the payload of a standalone gzip or xz compressed file such
+// as compressed firmware or a kernel module. The compression is detected by
+// magic, the payload is decompressed into a temp file, patched and compressed
+// again with the same format into a work file which atomically replaces the
+// original file.
+type Patcher struct {
+	path      string
+	fs        libfs.FS
+	now       func() time.Time
+	dryRun    bool
+	backupPol patcher.BackupPolicy
+	limits    libio.UnpackLimits
+	preserve  bool
+	result    chan<- patcher.Result
+	progress  patcher.ProgressFunc
+	logger    *zap.Logger
+}
+
+// New returns a patcher of the file at path, it is NewPatcher with the
+// WithResult and WithLogger options.
+func New(path string, result chan<- patcher.Result, logger *zap.Logger) *Patcher {
+	return NewPatcher(path, WithResult(result), WithLogger(logger))
+}
+
+// log returns the logger of a run: the given one or the one of ctx, with
+// the package and file path fields.
+func (p *Patcher) log(ctx context.Context) *zap.Logger {
+	logger := p.logger
+	if logger == nil {
+		logger = logging.FromContext(ctx)
+	}
+
+	return logger.With(logging.ZapFieldPkg("compresspatcher"), logging.ZapFieldPath(p.path))
+}
+
+// Patch applies patterns and returns the result, which is also sent to the
+// result channel when one was given. On failure the result only holds the
+// path and the error.
+func (p *Patcher) Patch(patterns []*patcher.Pattern, backup bool) (patcher.Result, error) {
+	return p.PatchContext(context.Background(), patterns, backup)
+}
+
+// PatchContext is like Patch but stops as soon as ctx is done, the file of a
+// canceled run is left untouched.
+func (p *Patcher) PatchContext(
+	ctx context.Context,
+	patterns []*patcher.Pattern,
+	backup bool,
+) (patcher.Result, error) {
+	result, err := p.run(ctx, patterns, backup)
+
+	return patcher.Deliver(p.result, p.path, result, err)
+}
+
+func (p *Patcher) run(ctx context.Context, patterns []*patcher.Pattern, backup bool) (patcher.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return patcher.Result{}, err
+	}
+
+	inFile, err := p.fs.OpenFile(p.path, os.O_RDONLY, 0)
+	if err != nil {
+		return patcher.Result{}, err //nolint:wrapcheck
+	}
+
+	defer inFile.Close()
+
+	run := &patcher.FileRun{
+		Path:       p.path,
+		BufferSize: bufferSize,
+		DryRun:     p.dryRun,
+		FilterELF:  libelf.FilterOffsets,
+		Progress:   p.progress,
+		Logger:     p.log(ctx),
+	}
+
+	input, err := patcher.HashReader(inFile)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	if _, err := inFile.Seek(0, io.SeekStart); err != nil {
+		return patcher.Result{}, fmt.Errorf("in file seek failed: %w", err)
+	}
+
+	var rawFile libio.File
+
+	if p.dryRun {
+		// A dry run never writes next to the file, the payload is kept in
+		// memory within the unpack limits.
+		rawFile = libio.NewBuffer(nil)
+	} else {
+		workFile, err := patcher.CreateWorkFile(p.fs, p.path, ".raw")
+		if err != nil {
+			return patcher.Result{}, err
+		}
+
+		defer p.removeWork(ctx, workFile)
+
+		rawFile = workFile
+	}
+
+	fileType, streams, err := p.unpack(ctx, run, inFile, rawFile, input.Size)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	input.Compression = fileType.String()
+
+	rawSize, err := libio.Size(rawFile)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	replaced, patternResults, err := run.Apply(ctx, rawFile, patterns, rawSize)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	result := patcher.NewResult(p.path, replaced)
+	result.Patterns = patternResults
+	result.Segments = []patcher.Segment{{Name: fileType.String(), Size: input.Size, Streams: streams}}
+	result.Input = input
+	result.Phases = run.Phases()
+
+	if p.dryRun || replaced == 0 {
+		return result, nil
+	}
+
+	outFile, err := patcher.CreateWorkFile(p.fs, p.path, "")
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	defer p.removeWork(ctx, outFile)
+
+	output, err := p.pack(ctx, run, rawFile, outFile, fileType, streams, rawSize)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	if backup {
+		if result.Backup, err = run.Backup(p.fs, p.backupPol, inFile, p.now()); err != nil {
+			return patcher.Result{}, err
+		}
+	}
+
+	if err := outFile.Commit(); err != nil {
+		return patcher.Result{}, err
+	}
+
+	result.Output = &output
+	result.Phases = run.Phases()
+
+	return result, nil
+}
+
+func (p *Patcher) removeWork(ctx context.Context, file *patcher.WorkFile) {
+	if err := file.Remove(); err != nil {
+		p.log(ctx).Warn("remove work file failed", zap.String("file", file.Name()), zap.Error(err))
+	}
+}
+
+// unpack decompresses inFile into rawFile and returns its compression with
+// its gzip members or xz streams.
+func (p *Patcher) unpack(
+	ctx context.Context,
+	run *patcher.FileRun,
+	inFile libfs.File,
+	rawFile io.Writer,
+	total int64,
+) (libcpio.HeaderTypeEnum, []libio.Stream, error) {
+	fileType, err := libcpio.HeaderTypeFromReader(inFile)
+	if err != nil {
+		return libcpio.HeaderTypeUnknown, nil, err
+	}
+
+	if _, err := inFile.Seek(0, io.SeekStart); err != nil {
+		return libcpio.HeaderTypeUnknown, nil, fmt.Errorf("in file seek failed: %w", err)
+	}
+
+	reader := run.Reader(ctx, inFile, patcher.PhaseUnpack, 0, total)
+
+	var streams []libio.Stream
+
+	switch fileType {
+	case libcpio.HeaderTypeXZ:
+		p.log(ctx).Info("unpack", logging.ZapFieldPhase(patcher.PhaseUnpack), logging.ZapFieldFormat(fileType))
+
+		if err = libio.UnpackXZ(rawFile, reader, p.limits); err != nil {
+			break
+		}
+
+		// The streams are only reported and preserved, a payload the decoder
+		// accepts is not failed when its indexes cannot be walked.
+		if streams, err = libio.XZStreams(inFile, total); err != nil {
+			p.log(ctx).Warn("xz streams unknown", logging.ZapFieldPhase(patcher.PhaseUnpack), zap.Error(err))
+
+			err = nil
+		}
+	case libcpio.HeaderTypeGZ:
+		p.log(ctx).Info("unpack", logging.ZapFieldPhase(patcher.PhaseUnpack), logging.ZapFieldFormat(fileType))
+
+		streams, err = libio.UnpackGZ(rawFile, reader, p.limits)
+	case libcpio.HeaderTypeCPIO, libcpio.HeaderTypeUnknown:
+		return libcpio.HeaderTypeUnknown, nil, &CompressionUnsupportedError{Path: p.path, Type: fileType}
+	}
+
+	if err != nil {
+		return libcpio.HeaderTypeUnknown, nil, err
+	}
+
+	return fileType, streams, ctx.Err()
+}
+
+func (p *Patcher) pack(
+	ctx context.Context,
+	run *patcher.FileRun,
+	rawFile, outFile io.ReadWriteSeeker,
+	fileType libcpio.HeaderTypeEnum,
+	streams []libio.Stream,
+	total int64,
+) (patcher.FileInfo, error) {
+	if _, err := rawFile.Seek(0, io.SeekStart); err != nil {
+		return patcher.FileInfo{}, fmt.Errorf("raw file seek failed: %w", err)
+	}
+
+	reader := run.Reader(ctx, rawFile, patcher.PhasePack, 0, total)
+
+	p.log(ctx).Info("pack", logging.ZapFieldPhase(patcher.PhasePack), logging.ZapFieldFormat(fileType))
+
+	if !p.preserve {
+		streams = nil
+	}
+
+	var err error
+
+	if fileType == libcpio.HeaderTypeXZ {
+		err = libio.PackXZStreams(outFile, reader, streams)
+	} else {
+		err = libio.PackGZStreams(outFile, reader, streams)
+	}
+
+	if err != nil {
+		return patcher.FileInfo{}, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return patcher.FileInfo{}, err
+	}
+
+	if _, err := outFile.Seek(0, io.SeekStart); err != nil {
+		return patcher.FileInfo{}, fmt.Errorf("out file seek failed: %w", err)
+	}
+
+	output, err := patcher.HashReader(outFile)
+	if err != nil {
+		return patcher.FileInfo{}, err
+	}
+
+	output.Compression = fileType.String()
+
+	return output, nil
+}
+
+type CompressionUnsupportedError struct {
+	Path string
+	Type libcpio.HeaderTypeEnum
+}
+
+func (e *CompressionUnsupportedError) Error() string {
+	return fmt.Sprintf("%s: %s is not a supported compressed file", e.Path, e.Type)
+}
+
+func (e *CompressionUnsupportedError) Unwrap() error {
+	return liberr.ErrUnsupported
+}
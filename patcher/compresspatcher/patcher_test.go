@@ -0,0 +1,272 @@
+package compresspatcher_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/compresspatcher"
+)
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte("firmware HELLO_WORLD blob HELLO_WORLD end")
+	expected := []byte("firmware HELLO_THERE blob HELLO_THERE end")
+
+	tests := []struct {
+		name   string
+		pack   func(dst *bytes.Buffer, src []byte) error
+		unpack func(dst *bytes.Buffer, src []byte) error
+	}{
+		{
+			name: "fw.gz",
+			pack: func(dst *bytes.Buffer, src []byte) error {
+				return libio.PackGZ(dst, bytes.NewReader(src))
+			},
+			unpack: func(dst *bytes.Buffer, src []byte) error {
+				reader, err := gzip.NewReader(bytes.NewReader(src))
+				if err != nil {
+					return err
+				}
+
+				_, err = dst.ReadFrom(reader)
+
+				return err
+			},
+		},
+		{
+			name: "fw.xz",
+			pack: func(dst *bytes.Buffer, src []byte) error {
+				return libio.PackXZ(dst, bytes.NewReader(src))
+			},
+			unpack: func(dst *bytes.Buffer, src []byte) error {
+				return libio.UnpackXZ(dst, bytes.NewReader(src), libio.UnpackLimits{})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var packed bytes.Buffer
+			if err := test.pack(&packed, payload); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), test.name)
+			if err := os.WriteFile(path, packed.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			result := make(chan patcher.Result, 1)
+			compresspatcher.New(path, result, zap.NewNop()).Patch([]*patcher.Pattern{{
+				Count:   2,
+				Search:  []byte("HELLO_WORLD"),
+				Replace: []byte("HELLO_THERE"),
+			}}, true)
+
+			if res := <-result; res.Err != nil || res.BytesPatched != 22 {
+				t.Fatalf("result non valid: %+v", res)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var unpacked bytes.Buffer
+			if err := test.unpack(&unpacked, data); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(unpacked.Bytes(), expected) {
+				t.Fatalf("payload non valid: %q", unpacked.Bytes())
+			}
+
+			backup, err := os.ReadFile(path + ".bak")
+			if err != nil || !bytes.Equal(backup, packed.Bytes()) {
+				t.Fatalf("backup non valid: %v", err)
+			}
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			if err != nil || len(entries) != 2 {
+				t.Fatalf("work files left: %v %v", entries, err)
+			}
+		})
+	}
+}
+
+func TestPatchPreserveStreams(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte("firmware HELLO_WORLD blob HELLO_WORLD end")
+	layout := []libio.Stream{{RawSize: 20, Padding: 4}, {}}
+
+	tests := []struct {
+		name    string
+		pack    func(dst *bytes.Buffer, src []byte) error
+		streams func(src []byte) ([]libio.Stream, error)
+	}{
+		{
+			name: "fw.gz",
+			pack: func(dst *bytes.Buffer, src []byte) error {
+				return libio.PackGZStreams(dst, bytes.NewReader(src), []libio.Stream{{RawSize: 20}, {}})
+			},
+			streams: func(src []byte) ([]libio.Stream, error) {
+				return libio.UnpackGZ(io.Discard, bytes.NewReader(src), libio.UnpackLimits{})
+			},
+		},
+		{
+			name: "fw.xz",
+			pack: func(dst *bytes.Buffer, src []byte) error {
+				return libio.PackXZStreams(dst, bytes.NewReader(src), layout)
+			},
+			streams: func(src []byte) ([]libio.Stream, error) {
+				return libio.XZStreams(bytes.NewReader(src), int64(len(src)))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var packed bytes.Buffer
+			if err := test.pack(&packed, payload); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), test.name)
+			if err := os.WriteFile(path, packed.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			result := make(chan patcher.Result, 1)
+			filePatcher := compresspatcher.NewPatcher(
+				path,
+				compresspatcher.WithResult(result),
+				compresspatcher.WithLogger(zap.NewNop()),
+				compresspatcher.WithPreserveStreams(true),
+			)
+			filePatcher.Patch([]*patcher.Pattern{{
+				Count:   2,
+				Search:  []byte("HELLO_WORLD"),
+				Replace: []byte("HELLO_THERE"),
+			}}, false)
+
+			res := <-result
+			if res.Err != nil || len(res.Segments) != 1 || len(res.Segments[0].Streams) != 2 {
+				t.Fatalf("result non valid: %+v", res)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			streams, err := test.streams(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for ind, stream := range streams {
+				expected := res.Segments[0].Streams[ind]
+				if len(streams) != 2 || stream.RawSize != expected.RawSize || stream.Padding != expected.Padding {
+					t.Fatalf("streams non valid: %+v, expected %+v", streams, res.Segments[0].Streams)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchMemFS(t *testing.T) {
+	t.Parallel()
+
+	var packed bytes.Buffer
+
+	if err := libio.PackGZ(&packed, bytes.NewReader([]byte("firmware HELLO_WORLD end"))); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := libfs.NewMemFS()
+	fsys.WriteFile("fw.gz", packed.Bytes())
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	patterns := []*patcher.Pattern{{Count: 1, Search: []byte("HELLO_WORLD"), Replace: []byte("HELLO_THERE")}}
+	opts := []compresspatcher.Option{
+		compresspatcher.WithClock(func() time.Time { return now }),
+		compresspatcher.WithBackupPolicy(patcher.BackupPolicy{Timestamp: true}),
+	}
+
+	dryRun := append([]compresspatcher.Option{
+		compresspatcher.WithFS(readOnlyFS{MemFS: fsys}),
+		compresspatcher.WithDryRun(true),
+	}, opts...)
+
+	p := compresspatcher.NewPatcher("fw.gz", dryRun...)
+	if res, err := p.Patch(patterns, true); err != nil || res.BytesPatched != 11 {
+		t.Fatalf("dry run result non valid: %+v %v", res, err)
+	}
+
+	if names := fsys.Names(); len(names) != 1 {
+		t.Fatalf("dry run left files: %v", names)
+	}
+
+	res, err := compresspatcher.NewPatcher("fw.gz", append(opts, compresspatcher.WithFS(fsys))...).Patch(patterns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := fsys.Names()
+	slices.Sort(names)
+
+	if res.Backup != "fw.gz.20240506T070809Z.bak" || !slices.Equal(names, []string{"fw.gz", res.Backup}) {
+		t.Fatalf("files non valid: %s %v", res.Backup, names)
+	}
+
+	data, err := fsys.ReadFile("fw.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw bytes.Buffer
+
+	if _, err := libio.UnpackGZ(&raw, bytes.NewReader(data), libio.UnpackLimits{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if raw.String() != "firmware HELLO_THERE end" {
+		t.Fatalf("payload non valid: %q", raw.String())
+	}
+}
+
+// readOnlyFS fails every open which could write, so a dry run cannot touch
+// the directory of the file.
+type readOnlyFS struct {
+	*libfs.MemFS
+}
+
+func (fsys readOnlyFS) OpenFile(name string, flag int, perm os.FileMode) (libfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+
+	return fsys.MemFS.OpenFile(name, flag, perm) //nolint:wrapcheck
+}
@@ -0,0 +1,37 @@
+package cpiopatcher
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+// PatchBatch patches every path with the same patterns using a bounded pool
+// of workers. Each job creates its own temp workspace under temp, so images
+// sharing a base name do not collide. Every option is applied to each job
+// patcher before it runs.
+func PatchBatch(
+	ctx context.Context,
+	temp string,
+	paths []string,
+	patterns []*patcher.Pattern,
+	backup bool,
+	workers int,
+	mode patcher.BatchModeEnum,
+	logger *zap.Logger,
+	opts ...Option,
+) []patcher.Result {
+	return patcher.RunBatch(ctx, paths, workers, mode, func(ctx context.Context, path string) patcher.Result {
+		p := New(temp, path, nil, logger)
+
+		for _, opt := range opts {
+			opt(p)
+		}
+
+		result, _ := p.PatchContext(ctx, patterns, backup)
+
+		return result
+	})
+}
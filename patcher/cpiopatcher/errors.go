@@ -1,33 +1,46 @@
 package cpiopatcher
 
-import "fmt"
+import (
+	"fmt"
 
-type InvalidOffsetsLengthError struct {
-	Path          string
-	PatternIndex  int
-	PatternsCount int
-	OffsetsLength int
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/patcher"
+)
+
+var (
+	ErrStreamSignaturePolicy = liberr.New(liberr.ErrInvalid, "module signature policy not supported by streaming")
+	ErrStreamPreserveStreams = liberr.New(liberr.ErrInvalid, "preserving compressed streams not supported by streaming")
+)
+
+type (
+	InvalidOffsetsLengthError = patcher.InvalidOffsetsLengthError
+	PatternNotFoundError      = patcher.PatternNotFoundError
+)
+
+type InsufficientSpaceError struct {
+	Path      string
+	Required  int64
+	Available uint64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("%s: insufficient space required[%d] > available[%d]", e.Path, e.Required, e.Available)
+}
+
+func (e *InsufficientSpaceError) Unwrap() error {
+	return liberr.ErrIO
 }
 
-func (e *InvalidOffsetsLengthError) Error() string {
-	return fmt.Sprintf(
-		"%s: pattern %d invalid offsets length offsets_len[%d] != pattern_count[%d]",
-		e.Path,
-		e.PatternIndex,
-		e.OffsetsLength,
-		e.PatternsCount,
-	)
+type OutputTooLargeError struct {
+	Path string
+	Size int64
+	Max  int64
 }
 
-type PatternNotFoundError struct {
-	Path         string
-	PatternIndex int
+func (e *OutputTooLargeError) Error() string {
+	return fmt.Sprintf("%s: output too large size[%d] > max[%d]", e.Path, e.Size, e.Max)
 }
 
-func (e *PatternNotFoundError) Error() string {
-	return fmt.Sprintf(
-		"%s: pattern %d not found",
-		e.Path,
-		e.PatternIndex,
-	)
+func (e *OutputTooLargeError) Unwrap() error {
+	return liberr.ErrInvalid
 }
@@ -0,0 +1,212 @@
+package cpiopatcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/logging"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+)
+
+// image is an input file cut into its optional uncompressed cpio header and
+// the decompressed raw archive kept in temp storage.
+type image struct {
+	inFile             libfs.File
+	input              io.ReadSeeker
+	mapped             *libio.Mmap
+	cpioFile           libio.File
+	rawFile            libio.File
+	fileType           libcpio.HeaderTypeEnum
+	cpioZeroFooterSize int64
+	compressedOffset   int64
+	streams            []libio.Stream
+	info               patcher.FileInfo
+	output             *patcher.FileInfo
+	backupPath         string
+	signaturePath      string
+}
+
+func (i *image) Close() {
+	for _, closer := range []io.Closer{i.rawFile, i.cpioFile} {
+		if closer != nil {
+			closer.Close()
+		}
+	}
+
+	i.releaseInput() //nolint:errcheck
+	i.inFile.Close()
+}
+
+// releaseInput unmaps the input file, it must be called before the input is replaced.
+func (i *image) releaseInput() error {
+	i.input = i.inFile
+
+	if i.mapped == nil {
+		return nil
+	}
+
+	mapped := i.mapped
+	i.mapped = nil
+
+	return mapped.Close()
+}
+
+func (p *Patcher) open(ctx context.Context, flag int) (*image, error) {
+	img, err := p.openInput(ctx, flag)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.load(ctx, img); err != nil {
+		img.Close()
+
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// openInput opens the image and cuts its cpio header, leaving the input
+// positioned right after the compressed payload magic.
+func (p *Patcher) openInput(ctx context.Context, flag int) (*image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	inFile, err := p.fs.OpenFile(p.path, flag, p.filePerm)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	img := &image{inFile: inFile, input: inFile}
+
+	if img.info, err = patcher.HashReader(inFile); err != nil {
+		img.Close()
+
+		return nil, err //nolint:wrapcheck
+	}
+
+	if err := p.digest.Verify(img.info); err != nil {
+		img.Close()
+
+		return nil, fmt.Errorf("%s: %w", p.path, err)
+	}
+
+	if err := p.verifyImage(inFile); err != nil {
+		img.Close()
+
+		return nil, err
+	}
+
+	if _, err := inFile.Seek(0, 0); err != nil {
+		img.Close()
+
+		return nil, fmt.Errorf("in file seek failed: %w", err)
+	}
+
+	if err := p.cut(ctx, img); err != nil {
+		img.Close()
+
+		return nil, err
+	}
+
+	return img, nil
+}
+
+func (p *Patcher) cut(ctx context.Context, img *image) error {
+	var err error
+
+	if osFile, ok := img.inFile.(*os.File); ok && p.storageMode == StorageModeMmap {
+		if img.mapped, err = libio.OpenMmap(osFile); err != nil {
+			return err
+		}
+
+		img.input = io.NewSectionReader(img.mapped, 0, int64(img.mapped.Len()))
+	}
+
+	if img.fileType, err = libcpio.HeaderTypeFromReader(img.input); err != nil {
+		return err
+	}
+
+	if img.fileType == libcpio.HeaderTypeCPIO {
+		p.log(ctx).Info("cut cpio header", logging.ZapFieldPhase(patcher.PhaseCut))
+		p.report(patcher.PhaseCut, 0, 0, 0)
+
+		if img.cpioFile, err = p.createTemp("cpio"); err != nil {
+			return fmt.Errorf("create cpio file failed: %w", err)
+		}
+
+		if img.fileType, img.cpioZeroFooterSize, err = libcpio.CutHeader(
+			img.input,
+			img.cpioFile,
+			p.bufferSize,
+		); err != nil {
+			return err
+		}
+	}
+
+	img.info.Compression = img.fileType.String()
+
+	return nil
+}
+
+func (p *Patcher) load(ctx context.Context, img *image) error {
+	var err error
+
+	if img.rawFile, err = p.createTemp("raw"); err != nil {
+		return err
+	}
+
+	return p.unpack(ctx, img, img.rawFile)
+}
+
+// segments describes the cpio header, its zero footer and the compressed payload of the input.
+func (i *image) segments() ([]patcher.Segment, error) {
+	var segments []patcher.Segment
+
+	if i.cpioFile != nil {
+		size, err := libio.Size(i.cpioFile)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		segments = append(segments, patcher.Segment{Name: "cpio", Size: size})
+
+		if i.cpioZeroFooterSize > 0 {
+			segments = append(segments, patcher.Segment{
+				Name:   "cpio-zero-footer",
+				Offset: size,
+				Size:   i.cpioZeroFooterSize,
+			})
+		}
+	}
+
+	return append(segments, i.payload()), nil
+}
+
+// payload describes the compressed payload, the zero padding following its
+// last stream is recorded with the alignment of the input end.
+func (i *image) payload() patcher.Segment {
+	segment := patcher.Segment{
+		Name:    i.fileType.String(),
+		Offset:  i.compressedOffset,
+		Size:    i.info.Size - i.compressedOffset,
+		Streams: i.streams,
+	}
+
+	if len(i.streams) > 0 {
+		segment.Padding = i.streams[len(i.streams)-1].Padding
+	}
+
+	if segment.Padding > 0 {
+		segment.Size -= segment.Padding
+		segment.Align = patcher.PaddingAlign(i.info.Size)
+	}
+
+	return segment
+}
@@ -0,0 +1,88 @@
+package cpiopatcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/librsa"
+	"github.com/grinderz/grgo/patcher"
+)
+
+const signatureExt = ".sig"
+
+// WithImageSignature enables detached signatures kept next to the image in
+// <image>.sig. The verifier checks the input signature before patching, the
+// signer replaces it with a signature of the patched image. Either may be nil.
+func WithImageSignature(signer *librsa.Signer, verifier *librsa.Verifier) Option {
+	return func(p *Patcher) {
+		p.imgSigner = signer
+		p.imgVerifier = verifier
+	}
+}
+
+func (p *Patcher) verifyImage(inFile libfs.File) error {
+	if p.imgVerifier == nil {
+		return nil
+	}
+
+	sigFile, err := p.fs.OpenFile(p.path+signatureExt, os.O_RDONLY, p.filePerm)
+	if err != nil {
+		return fmt.Errorf("%s: open detached signature failed: %w", p.path, err)
+	}
+
+	defer sigFile.Close()
+
+	signature, err := io.ReadAll(sigFile)
+	if err != nil {
+		return fmt.Errorf("%s: read detached signature failed: %w", p.path, err)
+	}
+
+	if _, err := inFile.Seek(0, 0); err != nil {
+		return fmt.Errorf("in file seek failed: %w", err)
+	}
+
+	if err := p.imgVerifier.Verify(inFile, signature); err != nil {
+		return fmt.Errorf("%s: %w", p.path, err)
+	}
+
+	return nil
+}
+
+// signImage signs the packed output and writes the detached signature into
+// a work file next to the image. The caller commits it after the image, so a
+// failed signing leaves both the image and its old signature in place.
+func (p *Patcher) signImage(ctx context.Context, outFile io.ReadSeeker) (*patcher.WorkFile, error) {
+	if p.imgSigner == nil {
+		if p.imgVerifier != nil {
+			p.log(ctx).Warn("detached signature invalidated")
+		}
+
+		return nil, nil
+	}
+
+	p.log(ctx).Info("sign image")
+
+	if _, err := outFile.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("out file seek failed: %w", err)
+	}
+
+	signature, err := p.imgSigner.Sign(outFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.path, err)
+	}
+
+	sigFile, err := patcher.CreateWorkFileAs(p.fs, p.path+signatureExt, p.path, "")
+	if err != nil {
+		return nil, fmt.Errorf("signature create failed: %w", err)
+	}
+
+	if _, err := sigFile.Write(signature); err != nil {
+		sigFile.Remove() //nolint:errcheck
+		return nil, fmt.Errorf("signature write failed: %w", err)
+	}
+
+	return sigFile, nil
+}
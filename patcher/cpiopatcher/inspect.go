@@ -0,0 +1,150 @@
+package cpiopatcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+)
+
+type Layout struct {
+	Path               string
+	Size               int64
+	Compression        libcpio.HeaderTypeEnum
+	CPIOHeaderSize     int64
+	CPIOZeroFooterSize int64
+	CompressedOffset   int64
+	CompressedSize     int64
+	Padding            int64
+	Align              int64
+	Streams            int
+	RawSize            int64
+}
+
+// Inspect unpacks the image into temp files and describes its segments.
+// The input file is opened read only and temp files are always removed.
+func (p *Patcher) Inspect(ctx context.Context) (Layout, error) {
+	defer p.cleanup(ctx)
+
+	img, err := p.open(ctx, os.O_RDONLY)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	defer img.Close()
+
+	layout := Layout{
+		Path:               p.path,
+		Compression:        img.fileType,
+		CPIOZeroFooterSize: img.cpioZeroFooterSize,
+		CompressedOffset:   img.compressedOffset,
+	}
+
+	if layout.Size, err = remainingSize(img.input, 0); err != nil {
+		return Layout{}, err
+	}
+
+	if layout.RawSize, err = remainingSize(img.rawFile, 0); err != nil {
+		return Layout{}, err
+	}
+
+	if img.cpioFile != nil {
+		if layout.CPIOHeaderSize, err = remainingSize(img.cpioFile, 0); err != nil {
+			return Layout{}, err
+		}
+	}
+
+	payload := img.payload()
+	layout.CompressedSize = payload.Size
+	layout.Padding = payload.Padding
+	layout.Align = payload.Align
+	layout.Streams = len(payload.Streams)
+
+	return layout, nil
+}
+
+// Members lists the members of the compressed cpio archive.
+func (p *Patcher) Members(ctx context.Context) ([]libcpio.Member, error) {
+	defer p.cleanup(ctx)
+
+	img, err := p.open(ctx, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+
+	defer img.Close()
+
+	if _, err := img.rawFile.Seek(0, 0); err != nil {
+		return nil, fmt.Errorf("raw seek failed: %w", err)
+	}
+
+	return libcpio.ListMembers(bufio.NewReaderSize(img.rawFile, p.bufferSize))
+}
+
+// Extract writes the data of the named member of the compressed cpio archive to dst.
+func (p *Patcher) Extract(ctx context.Context, name string, dst io.Writer) (int64, error) {
+	defer p.cleanup(ctx)
+
+	img, err := p.open(ctx, os.O_RDONLY)
+	if err != nil {
+		return 0, err
+	}
+
+	defer img.Close()
+
+	if _, err := img.rawFile.Seek(0, 0); err != nil {
+		return 0, fmt.Errorf("raw seek failed: %w", err)
+	}
+
+	offset, size, err := libcpio.FindMember(bufio.NewReaderSize(img.rawFile, p.bufferSize), name)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(dst, io.NewSectionReader(img.rawFile, offset, size))
+	if err != nil {
+		return 0, fmt.Errorf("extract copy failed: %w", err)
+	}
+
+	return written, nil
+}
+
+// Verify reports for every pattern whether the image still holds the
+// searched bytes, already holds the replacement or neither of them.
+func (p *Patcher) Verify(ctx context.Context, patterns []*patcher.Pattern) ([]patcher.PatternState, error) {
+	defer p.cleanup(ctx)
+
+	img, err := p.open(ctx, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+
+	defer img.Close()
+
+	states := make([]patcher.PatternState, 0, len(patterns))
+
+	for patternIndex, pattern := range patterns {
+		searchOffsets, err := p.search(ctx, img.rawFile, patternIndex, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		replaceOffsets, err := p.search(ctx, img.rawFile, patternIndex, pattern.Reverse())
+		if err != nil {
+			return nil, err
+		}
+
+		states = append(states, patcher.NewPatternState(
+			patternIndex,
+			pattern,
+			len(searchOffsets),
+			len(replaceOffsets),
+		))
+	}
+
+	return states, nil
+}
@@ -1,16 +1,31 @@
 package libcpio
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"path"
+	"strconv"
+	"strings"
 
 	cpio "github.com/grinderz/gocpio"
+
+	"github.com/grinderz/grgo/liberr"
 )
 
-const zeroByte = 0x00
+const (
+	zeroByte    = 0x00
+	trailerName = "TRAILER!!!"
+
+	newcMagic          = "070701"
+	newcHeaderSize     = 110
+	newcFileSizeOffset = 54
+	newcNameSizeOffset = 94
+	newcFieldSize      = 8
+	newcAlign          = 4
+)
 
-func findZeroFooterSize(inFile *os.File, buffSize int) (int64, error) {
+func findZeroFooterSize(inFile io.ReadSeeker, buffSize int) (int64, error) {
 	buff := make([]byte, buffSize)
 
 	var (
@@ -27,7 +42,7 @@ func findZeroFooterSize(inFile *os.File, buffSize int) (int64, error) {
 
 		totalRead += int64(readBytes)
 
-		for _, b := range buff {
+		for _, b := range buff[:readBytes] {
 			if b != zeroByte {
 				if _, err := inFile.Seek(-totalRead+index, 1); err != nil {
 					return 0, fmt.Errorf("file seek failed: %w", err)
@@ -39,13 +54,28 @@ func findZeroFooterSize(inFile *os.File, buffSize int) (int64, error) {
 		}
 
 		if err == io.EOF {
-			return 0, fmt.Errorf("read file EOF: %w", err)
+			return 0, fmt.Errorf("read file EOF: %w", liberr.Corrupt(err))
 		}
 	}
 }
 
-func findTrailer(file *os.File) (int64, error) {
-	rdr := cpio.NewReader(file)
+// fullReader fills every read, the cpio reader parses headers and names
+// from single reads and fails on the short reads of buffered readers.
+type fullReader struct {
+	reader io.Reader
+}
+
+func (r fullReader) Read(buff []byte) (int, error) {
+	readBytes, err := io.ReadFull(r.reader, buff)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+
+	return readBytes, err //nolint:wrapcheck
+}
+
+func findTrailer(file io.ReadSeeker) (int64, error) {
+	rdr := cpio.NewReader(fullReader{reader: file})
 
 	var (
 		hdr *cpio.Header
@@ -55,10 +85,10 @@ func findTrailer(file *os.File) (int64, error) {
 	for {
 		hdr, err = rdr.Next()
 		if err != nil {
-			return 0, fmt.Errorf("cpio reader failed: %w", err)
+			return 0, fmt.Errorf("cpio reader failed: %w", liberr.Corrupt(err))
 		}
 
-		if hdr.Name == "TRAILER!!!" {
+		if hdr.Name == trailerName {
 			break
 		}
 	}
@@ -70,7 +100,7 @@ func findTrailer(file *os.File) (int64, error) {
 	return rdr.Pos(), nil
 }
 
-func cut(dst io.Writer, src *os.File) error {
+func cut(dst io.Writer, src io.ReadSeeker) error {
 	if _, err := src.Seek(0, 0); err != nil {
 		return fmt.Errorf("src seek failed: %w", err)
 	}
@@ -99,7 +129,7 @@ func WriteHeader(dst io.Writer, reader io.Reader, footerSize int64) error {
 	return nil
 }
 
-func CutHeader(inFile, cpioFile *os.File, bufferSize int) (HeaderTypeEnum, int64, error) {
+func CutHeader(inFile io.ReadSeeker, cpioFile io.Writer, bufferSize int) (HeaderTypeEnum, int64, error) {
 	if err := cut(cpioFile, inFile); err != nil {
 		return HeaderTypeUnknown, 0, err
 	}
@@ -116,3 +146,163 @@ func CutHeader(inFile, cpioFile *os.File, bufferSize int) (HeaderTypeEnum, int64
 
 	return fileType, cpioZeroFooterSize, nil
 }
+
+// FindMember returns the offset and size of the named member data in a cpio
+// archive read from the beginning of reader. Leading "./" and "/" are ignored
+// when comparing names.
+func FindMember(reader io.Reader, name string) (int64, int64, error) {
+	rdr := cpio.NewReader(fullReader{reader: reader})
+	name = cleanMemberName(name)
+
+	for {
+		hdr, err := rdr.Next()
+		if err != nil {
+			return 0, 0, fmt.Errorf("cpio reader failed: %w", liberr.Corrupt(err))
+		}
+
+		if hdr.Name == trailerName {
+			return 0, 0, &MemberNotFoundError{Name: name}
+		}
+
+		if cleanMemberName(hdr.Name) == name {
+			return rdr.Pos(), hdr.Size, nil
+		}
+	}
+}
+
+func cleanMemberName(name string) string {
+	return strings.TrimLeft(path.Clean("/"+name), "/")
+}
+
+type MemberNotFoundError struct {
+	Name string
+}
+
+func (e *MemberNotFoundError) Error() string {
+	return fmt.Sprintf("cpio member %s not found", e.Name)
+}
+
+func (e *MemberNotFoundError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type Member struct {
+	Name   string
+	Mode   int64
+	Type   int64
+	Size   int64
+	Offset int64
+}
+
+// ListMembers returns every member of a cpio archive read from the beginning
+// of reader, offsets point at the member data.
+func ListMembers(reader io.Reader) ([]Member, error) {
+	rdr := cpio.NewReader(fullReader{reader: reader})
+	members := make([]Member, 0)
+
+	for {
+		hdr, err := rdr.Next()
+		if err != nil {
+			return nil, fmt.Errorf("cpio reader failed: %w", liberr.Corrupt(err))
+		}
+
+		if hdr.Name == trailerName {
+			return members, nil
+		}
+
+		members = append(members, Member{
+			Name:   hdr.Name,
+			Mode:   hdr.Mode,
+			Type:   hdr.Type,
+			Size:   hdr.Size,
+			Offset: rdr.Pos(),
+		})
+	}
+}
+
+// ReplaceMember copies the newc cpio archive read from src to dst with the
+// data of the named member replaced by size bytes of data. Other headers are
+// copied unchanged, so inode numbers and hard links survive.
+func ReplaceMember(dst io.Writer, src io.Reader, name string, data io.Reader, size int64) error {
+	name = cleanMemberName(name)
+	header := make([]byte, newcHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(src, header); err != nil {
+			return fmt.Errorf("cpio header read failed: %w", liberr.Corrupt(err))
+		}
+
+		if string(header[:len(newcMagic)]) != newcMagic {
+			return liberr.Corrupt(cpio.ErrInvalidHeader)
+		}
+
+		nameSize, err := newcField(header, newcNameSizeOffset)
+		if err != nil {
+			return err
+		}
+
+		fileSize, err := newcField(header, newcFileSizeOffset)
+		if err != nil {
+			return err
+		}
+
+		nameBuff := make([]byte, newcAligned(newcHeaderSize+nameSize)-newcHeaderSize)
+		if _, err := io.ReadFull(src, nameBuff); err != nil {
+			return fmt.Errorf("cpio name read failed: %w", liberr.Corrupt(err))
+		}
+
+		memberName := strings.TrimRight(string(nameBuff), "\x00")
+		replace := memberName != trailerName && cleanMemberName(memberName) == name
+
+		if replace {
+			copy(header[newcFileSizeOffset:], fmt.Sprintf("%08x", size))
+		}
+
+		if _, err := dst.Write(append(header, nameBuff...)); err != nil {
+			return fmt.Errorf("cpio header write failed: %w", err)
+		}
+
+		if memberName == trailerName {
+			return &MemberNotFoundError{Name: name}
+		}
+
+		if !replace {
+			if _, err := io.CopyN(dst, src, newcAligned(fileSize)); err != nil {
+				return fmt.Errorf("cpio member copy failed: %w", liberr.Corrupt(err))
+			}
+
+			continue
+		}
+
+		if _, err := io.CopyN(io.Discard, src, newcAligned(fileSize)); err != nil {
+			return fmt.Errorf("cpio member skip failed: %w", liberr.Corrupt(err))
+		}
+
+		if _, err := io.CopyN(dst, data, size); err != nil {
+			return fmt.Errorf("cpio member write failed: %w", err)
+		}
+
+		if _, err := dst.Write(make([]byte, newcAligned(size)-size)); err != nil {
+			return fmt.Errorf("cpio member pad failed: %w", err)
+		}
+
+		if _, err := io.Copy(dst, src); err != nil {
+			return fmt.Errorf("cpio archive copy failed: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func newcField(header []byte, offset int) (int64, error) {
+	value, err := strconv.ParseInt(string(header[offset:offset+newcFieldSize]), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cpio header field parse failed: %w", liberr.Corrupt(err))
+	}
+
+	return value, nil
+}
+
+func newcAligned(size int64) int64 {
+	return (size + newcAlign - 1) &^ (newcAlign - 1)
+}
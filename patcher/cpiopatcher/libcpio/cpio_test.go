@@ -0,0 +1,77 @@
+package libcpio_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/iotest"
+
+	cpio "github.com/grinderz/gocpio"
+
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+)
+
+func newArchive(t *testing.T) []byte {
+	t.Helper()
+
+	var buff bytes.Buffer
+
+	writer := cpio.NewWriter(&buff)
+
+	for _, member := range []struct {
+		name string
+		data string
+	}{
+		{name: "etc/os-release", data: "NAME=test\n"},
+		{name: "lib/modules/kernel-module-with-a-long-name.ko", data: "HELLO_WORLD"},
+	} {
+		if err := writer.WriteHeader(&cpio.Header{
+			Mode: 0o644,
+			Type: cpio.TYPE_REG,
+			Size: int64(len(member.data)),
+			Name: member.name,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := writer.Write([]byte(member.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buff.Bytes()
+}
+
+func TestFindMemberShortReads(t *testing.T) {
+	t.Parallel()
+
+	archive := newArchive(t)
+
+	offset, size, err := libcpio.FindMember(
+		iotest.OneByteReader(bytes.NewReader(archive)),
+		"/lib/modules/kernel-module-with-a-long-name.ko",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data := string(archive[offset : offset+size]); data != "HELLO_WORLD" {
+		t.Fatalf("member data non valid: %q", data)
+	}
+}
+
+func TestListMembersShortReads(t *testing.T) {
+	t.Parallel()
+
+	members, err := libcpio.ListMembers(iotest.OneByteReader(bytes.NewReader(newArchive(t))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(members) != 2 || members[1].Name != "lib/modules/kernel-module-with-a-long-name.ko" {
+		t.Fatalf("members non valid: %+v", members)
+	}
+}
@@ -5,6 +5,8 @@
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/grinderz/grgo/liberr"
 )
 
 const MaxMagicSize = 6
@@ -76,7 +78,7 @@ func HeaderTypeFromString(value string) HeaderTypeEnum {
 func HeaderTypeFromReader(r io.Reader) (HeaderTypeEnum, error) {
 	buff := make([]byte, MaxMagicSize)
 	if _, err := io.ReadFull(r, buff); err != nil {
-		return HeaderTypeUnknown, fmt.Errorf("read reader failed: %w", err)
+		return HeaderTypeUnknown, fmt.Errorf("read reader failed: %w", liberr.Corrupt(err))
 	}
 
 	if bytes.Equal(buff, cpioMagic) {
@@ -104,6 +106,10 @@ func (e *HeaderTypeValueError) Error() string {
 	return fmt.Sprintf("cpio header type invalid value: %s", e.Value)
 }
 
+func (e *HeaderTypeValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
 type HeaderTypeUnsupportedFormatError struct {
 	Format []byte
 }
@@ -111,3 +117,7 @@ type HeaderTypeUnsupportedFormatError struct {
 func (e *HeaderTypeUnsupportedFormatError) Error() string {
 	return fmt.Sprintf("cpio header unsupported format %x", e.Format)
 }
+
+func (e *HeaderTypeUnsupportedFormatError) Unwrap() error {
+	return liberr.ErrUnsupported
+}
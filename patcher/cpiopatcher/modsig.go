@@ -0,0 +1,135 @@
+package cpiopatcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/grinderz/grgo/logging"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+	"github.com/grinderz/grgo/patcher/libmodsig"
+)
+
+// signatures finds the members patched by patterns which carry an appended
+// module signature and applies the signature policy to them.
+func (p *Patcher) signatures(ctx context.Context, img *image, patterns []patcher.PatternResult) ([]string, error) {
+	var offsets []int64
+
+	for _, pattern := range patterns {
+		offsets = append(offsets, pattern.Offsets...)
+	}
+
+	if len(offsets) == 0 {
+		return nil, nil
+	}
+
+	if _, err := img.rawFile.Seek(0, 0); err != nil {
+		return nil, fmt.Errorf("raw seek failed: %w", err)
+	}
+
+	members, err := libcpio.ListMembers(bufio.NewReaderSize(img.rawFile, p.bufferSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var signed []string
+
+	for _, member := range members {
+		if !containsOffset(member, offsets) {
+			continue
+		}
+
+		_, ok, err := libmodsig.Find(io.NewSectionReader(img.rawFile, member.Offset, member.Size), member.Size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: member %s: %w", p.path, member.Name, err)
+		}
+
+		if ok {
+			signed = append(signed, member.Name)
+		}
+	}
+
+	for ind, name := range signed {
+		if p.sigPolicy != patcher.SignaturePolicyStrip && p.sigPolicy != patcher.SignaturePolicyResign {
+			p.log(ctx).Warn("module signature invalidated", logging.ZapFieldMember(name))
+			continue
+		}
+
+		if err := p.replaceSignature(ctx, img, name, ind); err != nil {
+			return nil, fmt.Errorf("%s: member %s: %w", p.path, name, err)
+		}
+	}
+
+	return signed, nil
+}
+
+// replaceSignature rewrites the raw archive with the signature of the named
+// member stripped, or replaced by a new one.
+func (p *Patcher) replaceSignature(ctx context.Context, img *image, name string, index int) error {
+	if _, err := img.rawFile.Seek(0, 0); err != nil {
+		return fmt.Errorf("raw seek failed: %w", err)
+	}
+
+	start, size, err := libcpio.FindMember(bufio.NewReaderSize(img.rawFile, p.bufferSize), name)
+	if err != nil {
+		return err
+	}
+
+	sig, _, err := libmodsig.Find(io.NewSectionReader(img.rawFile, start, size), size)
+	if err != nil {
+		return err
+	}
+
+	var appendix []byte
+
+	if p.sigPolicy == patcher.SignaturePolicyResign {
+		p.log(ctx).Info("resign module", logging.ZapFieldMember(name))
+
+		if appendix, err = p.signer.Sign(io.NewSectionReader(img.rawFile, start, sig.Offset)); err != nil {
+			return err //nolint:wrapcheck
+		}
+	} else {
+		p.log(ctx).Info("strip module signature", logging.ZapFieldMember(name))
+	}
+
+	rawFile, err := p.createTemp(fmt.Sprintf("sig%d", index))
+	if err != nil {
+		return fmt.Errorf("create raw file failed: %w", err)
+	}
+
+	if _, err := img.rawFile.Seek(0, 0); err != nil {
+		rawFile.Close()
+
+		return fmt.Errorf("raw seek failed: %w", err)
+	}
+
+	if err := libcpio.ReplaceMember(
+		rawFile,
+		bufio.NewReaderSize(img.rawFile, p.bufferSize),
+		name,
+		io.MultiReader(io.NewSectionReader(img.rawFile, start, sig.Offset), bytes.NewReader(appendix)),
+		sig.Offset+int64(len(appendix)),
+	); err != nil {
+		rawFile.Close()
+
+		return err
+	}
+
+	img.rawFile.Close()
+	img.rawFile = rawFile
+
+	return nil
+}
+
+func containsOffset(member libcpio.Member, offsets []int64) bool {
+	for _, offset := range offsets {
+		if offset >= member.Offset && offset < member.Offset+member.Size {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+package cpiopatcher
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+	"github.com/grinderz/grgo/patcher/libmodsig"
+)
+
+// Option configures a Patcher built by NewPatcher.
+type Option func(p *Patcher)
+
+// NewPatcher returns a patcher of the image at path. Without options it
+// creates its temp workspaces in the OS temp directory of the OS
+// filesystem, reads with 8 KiB buffers, creates files with 0644
+// permissions, bounds the payload to the default unpack size, repacks it
+// with gzip, keeps backups next to the image and logs to the logger of the
+// context of every run.
+func NewPatcher(path string, opts ...Option) *Patcher {
+	p := &Patcher{
+		path:        path,
+		fileName:    filepath.Base(path),
+		bufferSize:  defaultBufferSize,
+		filePerm:    defaultFilePerm,
+		compression: libcpio.HeaderTypeGZ,
+		now:         time.Now,
+		storageMode: StorageModeFile,
+		limits:      libio.UnpackLimits{MaxSize: libio.DefaultMaxUnpackSize},
+		fs:          libfs.NewOSFS(),
+		sigPolicy:   patcher.SignaturePolicyKeep,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithTempDir creates the temp workspaces under dir.
+func WithTempDir(dir string) Option {
+	return func(p *Patcher) {
+		p.tempDir = dir
+	}
+}
+
+// WithResult makes Patch send its result to result.
+func WithResult(result chan<- patcher.Result) Option {
+	return func(p *Patcher) {
+		p.result = result
+	}
+}
+
+// WithLogger logs to logger, a nil logger selects the logger of the context
+// of every run.
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Patcher) {
+		p.logger = logger
+	}
+}
+
+// WithBufferSize reads the archive and searches patterns with buffers of
+// size bytes, a non positive size keeps the default.
+func WithBufferSize(size int) Option {
+	return func(p *Patcher) {
+		if size > 0 {
+			p.bufferSize = size
+		}
+	}
+}
+
+// WithFilePerm creates the image, its signature and the temp files with perm.
+func WithFilePerm(perm os.FileMode) Option {
+	return func(p *Patcher) {
+		p.filePerm = perm
+	}
+}
+
+// WithUnpackLimits bounds the decompressed size, compression ratio and xz
+// dictionary size of the payload, it defaults to a 500 MiB size limit.
+func WithUnpackLimits(limits libio.UnpackLimits) Option {
+	return func(p *Patcher) {
+		p.limits = limits
+	}
+}
+
+// WithCompression repacks the payload with compression, gzip or xz,
+// whatever the input compression.
+func WithCompression(compression libcpio.HeaderTypeEnum) Option {
+	return func(p *Patcher) {
+		p.compression = compression
+	}
+}
+
+// WithBackupPolicy selects the location, naming and retention of backups
+// written when Patch is asked to keep one.
+func WithBackupPolicy(policy patcher.BackupPolicy) Option {
+	return func(p *Patcher) {
+		p.backupPol = policy
+	}
+}
+
+// WithClock uses now to timestamp backups.
+func WithClock(now func() time.Time) Option {
+	return func(p *Patcher) {
+		p.now = now
+	}
+}
+
+// WithFS replaces the OS filesystem used to open the image, write the backup
+// and create temp files, so images can be patched in memory or inside other
+// containers. The mmap storage mode falls back to memory for non OS files.
+func WithFS(fsys libfs.FS) Option {
+	return func(p *Patcher) {
+		p.fs = fsys
+	}
+}
+
+// WithStorage selects where the intermediate cpio header, raw archive and
+// packed output are kept. In memory and mmap modes a buffer growing past
+// memoryLimit is moved into a temp file, a non positive limit disables it.
+// The mmap mode also maps the input image instead of reading it.
+func WithStorage(mode StorageModeEnum, memoryLimit int64) Option {
+	return func(p *Patcher) {
+		p.storageMode = mode
+		p.memoryLimit = memoryLimit
+	}
+}
+
+// WithProgress registers a callback invoked from the patching goroutine
+// each time a phase consumes more of its input.
+func WithProgress(progress patcher.ProgressFunc) Option {
+	return func(p *Patcher) {
+		p.progress = progress
+	}
+}
+
+// WithModuleSignature selects what happens to the appended signatures of
+// patched kernel modules, signer is only used by the resign policy.
+func WithModuleSignature(policy patcher.SignaturePolicyEnum, signer *libmodsig.Signer) Option {
+	return func(p *Patcher) {
+		p.sigPolicy = policy
+		p.signer = signer
+	}
+}
+
+// WithExpectedDigest refuses to patch an image which does not hash to
+// digest, the zero Digest accepts any image.
+func WithExpectedDigest(digest patcher.Digest) Option {
+	return func(p *Patcher) {
+		p.digest = digest
+	}
+}
+
+// WithKeepTemp keeps the temp workspace of every run for debugging instead
+// of removing it once the run is over.
+func WithKeepTemp(keep bool) Option {
+	return func(p *Patcher) {
+		p.keepTemp = keep
+	}
+}
+
+// WithPreserveStreams repacks a payload made of several gzip members or xz
+// streams into as many ones holding the same uncompressed sizes, instead of
+// a single one. Such a payload keeps the input compression whatever the
+// WithCompression option. Streams are not supported by streaming.
+func WithPreserveStreams(preserve bool) Option {
+	return func(p *Patcher) {
+		p.preserveStreams = preserve
+	}
+}
+
+// WithPaddingPolicy selects how the trailing zero padding recorded after the
+// segment named segment, such as "gz" or "xz" for the compressed payload, is
+// restored on repack. Segments default to PaddingPolicyAlign.
+func WithPaddingPolicy(segment string, policy patcher.PaddingPolicyEnum) Option {
+	return func(p *Patcher) {
+		if p.paddingPols == nil {
+			p.paddingPols = make(map[string]patcher.PaddingPolicyEnum)
+		}
+
+		p.paddingPols[segment] = policy
+	}
+}
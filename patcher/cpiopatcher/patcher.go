@@ -1,218 +1,666 @@
 package cpiopatcher
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/grinderz/grgo/libfs"
 	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/librsa"
+	"github.com/grinderz/grgo/logging"
 	"github.com/grinderz/grgo/patcher"
 	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+	"github.com/grinderz/grgo/patcher/libelf"
+	"github.com/grinderz/grgo/patcher/libmodsig"
 )
 
 const (
-	bufferSize         = 8192
-	filePerm           = 0644
-	maxDecompressBytes = 524_288_000
+	defaultBufferSize = 8192
+	defaultFilePerm   = 0644
 )
 
+var _ patcher.Patcher = (*Patcher)(nil)
+
 type Patcher struct {
-	tempDir            string
-	path               string
-	fileName           string
-	cpioZeroFooterSize int64
-	result             chan<- patcher.Result
-	logger             *zap.Logger
+	tempDir         string
+	path            string
+	fileName        string
+	bufferSize      int
+	filePerm        os.FileMode
+	compression     libcpio.HeaderTypeEnum
+	now             func() time.Time
+	workDir         string
+	keepTemp        bool
+	maxOutputSize   int64
+	limits          libio.UnpackLimits
+	preserveStreams bool
+	paddingPols     map[string]patcher.PaddingPolicyEnum
+	storageMode     StorageModeEnum
+	memoryLimit     int64
+	fs              libfs.FS
+	streaming       bool
+	sigPolicy       patcher.SignaturePolicyEnum
+	signer          *libmodsig.Signer
+	digest          patcher.Digest
+	backupPol       patcher.BackupPolicy
+	imgSigner       *librsa.Signer
+	imgVerifier     *librsa.Verifier
+	result          chan<- patcher.Result
+	progress        patcher.ProgressFunc
+	timer           *patcher.PhaseTimer
+	logger          *zap.Logger
 }
 
+// New returns a patcher of the image at path using temp for its temp
+// workspaces, it is NewPatcher with the WithTempDir, WithResult and
+// WithLogger options.
 func New(temp, path string, result chan<- patcher.Result, logger *zap.Logger) *Patcher {
-	return &Patcher{
-		tempDir:  temp,
-		path:     path,
-		fileName: filepath.Base(path),
-		result:   result,
-		logger:   logger,
+	return NewPatcher(path, WithTempDir(temp), WithResult(result), WithLogger(logger))
+}
+
+// Patch applies patterns and returns the result, which is also sent to the
+// result channel when one was given. On failure the result only holds the
+// path and the error.
+func (p *Patcher) Patch(patterns []*patcher.Pattern, backup bool) (patcher.Result, error) {
+	return p.PatchContext(context.Background(), patterns, backup)
+}
+
+// PatchContext is like Patch but stops as soon as ctx is done, the input
+// file of a canceled run is left untouched.
+func (p *Patcher) PatchContext(
+	ctx context.Context,
+	patterns []*patcher.Pattern,
+	backup bool,
+) (patcher.Result, error) {
+	result, err := p.run(ctx, patterns, backup, false)
+	if err == nil {
+		p.logResult(ctx, result)
 	}
+
+	return patcher.Deliver(p.result, p.path, result, err)
 }
 
-func (p *Patcher) Patch(patterns []*patcher.Pattern, backup bool) {
-	var inFile, cpioFile, rawFile *os.File
+// Repack decompresses the image and packs it again without patching.
+func (p *Patcher) Repack(ctx context.Context, backup bool) error {
+	_, err := p.run(ctx, nil, backup, true)
+
+	return err
+}
+
+func (p *Patcher) run(
+	ctx context.Context,
+	patterns []*patcher.Pattern,
+	backup, force bool,
+) (patcher.Result, error) {
+	defer p.cleanup(ctx)
+
+	if p.sigPolicy == patcher.SignaturePolicyResign && p.signer == nil {
+		return patcher.Result{}, patcher.ErrModuleSignerRequired
+	}
+
+	if p.compression != libcpio.HeaderTypeGZ && p.compression != libcpio.HeaderTypeXZ {
+		return patcher.Result{}, &libcpio.HeaderTypeValueError{Value: p.compression.String()}
+	}
 
-	inFile, err := os.OpenFile(p.path, os.O_RDWR, filePerm)
+	p.timer = &patcher.PhaseTimer{}
+
+	if p.streaming {
+		return p.stream(ctx, patterns, backup, force)
+	}
+
+	img, err := p.openInput(ctx, os.O_RDONLY)
 	if err != nil {
-		p.result <- patcher.NewError(p.path, err)
-		return
+		return patcher.Result{}, err
+	}
+
+	defer img.Close()
+
+	if err := p.preflight(img, backup); err != nil {
+		return patcher.Result{}, err
 	}
 
-	defer inFile.Close()
+	if err := p.load(ctx, img); err != nil {
+		return patcher.Result{}, err
+	}
 
-	fileType, err := libcpio.HeaderTypeFromReader(inFile)
+	replaced, patternResults, err := p.patch(ctx, img.rawFile, patterns)
 	if err != nil {
-		p.result <- patcher.NewError(p.path, err)
-		return
+		return patcher.Result{}, err
 	}
 
-	if fileType == libcpio.HeaderTypeCPIO {
-		p.logger.Info(fmt.Sprintf("%s: cut cpio header", p.path))
+	result, err := p.newResult(img, replaced, patternResults)
+	if err != nil || (replaced == 0 && !force) {
+		return result, err
+	}
+
+	if result.Signed, err = p.signatures(ctx, img, patternResults); err != nil {
+		return patcher.Result{}, err
+	}
 
-		cpioFile, err := os.Create(filepath.Join(p.tempDir, fmt.Sprintf("%s.cpio", p.fileName)))
+	if err := p.pack(ctx, img, backup); err != nil {
+		return patcher.Result{}, err
+	}
+
+	return p.completeResult(img, result), nil
+}
+
+func (p *Patcher) newResult(img *image, replaced int, patterns []patcher.PatternResult) (patcher.Result, error) {
+	segments, err := img.segments()
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	result := patcher.NewResult(p.path, replaced)
+	result.Patterns = patterns
+	result.Segments = segments
+	result.Input = img.info
+	result.Phases = p.timer.Phases()
+
+	return result, nil
+}
+
+// completeResult adds what is known once the image was rewritten.
+func (p *Patcher) completeResult(img *image, result patcher.Result) patcher.Result {
+	result.Backup = img.backupPath
+	result.SignaturePath = img.signaturePath
+	result.Output = img.output
+	result.Phases = p.timer.Phases()
+
+	return result
+}
+
+func (p *Patcher) createTemp(ext string) (libio.File, error) {
+	if p.storageMode == StorageModeFile {
+		return p.createTempFile(ext)
+	}
+
+	limit := p.memoryLimit
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+
+	return libio.NewSpillFile(limit, func() (libio.File, error) {
+		return p.createTempFile(ext)
+	}), nil
+}
+
+// createTempFile creates a file in the workspace of the run, the workspace
+// is a unique directory under tempDir created by the first temp file.
+func (p *Patcher) createTempFile(ext string) (libio.File, error) {
+	if len(p.workDir) == 0 {
+		workDir, err := p.fs.MkdirTemp(p.tempDir, p.fileName+".*")
 		if err != nil {
-			p.result <- patcher.NewError(p.path, fmt.Errorf("create cpio file failed: %w", err))
-			return
+			return nil, fmt.Errorf("create temp workspace failed: %w", err)
 		}
 
-		defer cpioFile.Close()
+		p.workDir = workDir
+	}
 
-		if fileType, p.cpioZeroFooterSize, err = libcpio.CutHeader(inFile, cpioFile, bufferSize); err != nil {
-			p.result <- patcher.NewError(p.path, err)
-			return
-		}
+	file, err := libfs.Create(p.fs, filepath.Join(p.workDir, fmt.Sprintf("%s.%s", p.fileName, ext)), p.filePerm)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
 	}
 
-	if rawFile, err = os.Create(filepath.Join(p.tempDir, fmt.Sprintf("%s.raw", p.fileName))); err != nil {
-		p.result <- patcher.NewError(p.path, err)
-		return
+	return file, nil
+}
+
+// log returns the logger of a run: the given one or the one of ctx, with
+// the package and image path fields.
+func (p *Patcher) log(ctx context.Context) *zap.Logger {
+	logger := p.logger
+	if logger == nil {
+		logger = logging.FromContext(ctx)
 	}
 
-	defer rawFile.Close()
+	return logger.With(logging.ZapFieldPkg("cpiopatcher"), logging.ZapFieldPath(p.path))
+}
+
+// logResult logs the patched bytes count and the time spent in every phase.
+func (p *Patcher) logResult(ctx context.Context, result patcher.Result) {
+	var total time.Duration
 
-	if err := p.unpack(rawFile, inFile, fileType); err != nil {
-		p.result <- patcher.NewError(p.path, err)
-		return
+	for _, phase := range result.Phases {
+		p.log(ctx).Debug("phase done", logging.ZapFieldPhase(phase.Phase), logging.ZapFieldDuration(phase.Elapsed))
+
+		total += phase.Elapsed
 	}
 
-	replaced, err := p.patch(rawFile, patterns)
-	if err != nil {
-		p.result <- patcher.NewError(p.path, err)
+	p.log(ctx).Info("done", logging.ZapFieldBytes(int64(result.BytesPatched)), logging.ZapFieldDuration(total))
+}
+
+// cleanup removes the workspace of the run whatever its outcome, unless
+// temp files are kept. Temp files must be closed first.
+func (p *Patcher) cleanup(ctx context.Context) {
+	if len(p.workDir) == 0 {
 		return
 	}
 
-	if replaced == 0 {
-		p.result <- patcher.NewResult(p.path, 0)
+	workDir := p.workDir
+	p.workDir = ""
+
+	if p.keepTemp {
+		p.log(ctx).Info("temp files kept", logging.ZapFieldDir(workDir))
 		return
 	}
 
-	if err := p.pack(rawFile, inFile, cpioFile, backup); err != nil {
-		p.result <- patcher.NewError(p.path, err)
-		return
+	if err := p.fs.RemoveAll(workDir); err != nil {
+		p.log(ctx).Warn("remove temp workspace failed", logging.ZapFieldDir(workDir), zap.Error(err))
 	}
+}
 
-	p.result <- patcher.NewResult(p.path, replaced)
+func (p *Patcher) report(phase patcher.PhaseEnum, patternIndex int, processed, total int64) {
+	p.timer.Start(phase)
+	p.progress.Report(p.path, phase, patternIndex, processed, total)
 }
 
-func (p *Patcher) backup(inFile *os.File) error {
-	p.logger.Info(fmt.Sprintf("%s: backup", p.path))
+func (p *Patcher) reader(
+	ctx context.Context,
+	reader io.Reader,
+	phase patcher.PhaseEnum,
+	patternIndex int,
+	total int64,
+) io.Reader {
+	p.timer.Start(phase)
+
+	return p.progress.Reader(ctx, reader, p.path, phase, patternIndex, total)
+}
+
+func (p *Patcher) backup(ctx context.Context, inFile io.ReadSeeker) (string, error) {
+	p.log(ctx).Info("backup", logging.ZapFieldPhase(patcher.PhaseBackup))
+	p.report(patcher.PhaseBackup, 0, 0, 0)
 
 	if _, err := inFile.Seek(0, 0); err != nil {
-		return fmt.Errorf("file seek failed: %w", err)
+		return "", fmt.Errorf("file seek failed: %w", err)
 	}
 
-	return libio.CloneReader(inFile, fmt.Sprintf("%s.bak", p.path))
+	return p.backupPol.Write(p.fs, p.path, inFile, p.now())
 }
 
-func (p *Patcher) unpack(rawFile, inFile *os.File, fileType libcpio.HeaderTypeEnum) error {
-	if _, err := inFile.Seek(-libcpio.MaxMagicSize, 1); err != nil {
+func (p *Patcher) unpack(ctx context.Context, img *image, dst io.Writer) error {
+	offset, err := img.input.Seek(-libcpio.MaxMagicSize, 1)
+	if err != nil {
 		return fmt.Errorf("in file seek failed: %w", err)
 	}
 
-	switch fileType {
+	img.compressedOffset = offset
+
+	total, err := remainingSize(img.input, offset)
+	if err != nil {
+		return err
+	}
+
+	reader := p.reader(ctx, img.input, patcher.PhaseUnpack, 0, total)
+
+	switch img.fileType {
 	case libcpio.HeaderTypeXZ:
-		p.logger.Info(fmt.Sprintf("%s: unpack xz", p.path))
+		p.log(ctx).Info("unpack", logging.ZapFieldPhase(patcher.PhaseUnpack), logging.ZapFieldFormat(img.fileType))
 
-		if err := libio.UnpackXZ(rawFile, inFile); err != nil {
+		if err := libio.UnpackXZ(dst, reader, p.limits); err != nil {
 			return err
 		}
+
+		// The streams are only reported and preserved, a payload the decoder
+		// accepts is not failed when its indexes cannot be walked.
+		payload := io.NewSectionReader(img.inFile, offset, img.info.Size-offset)
+		if img.streams, err = libio.XZStreams(payload, payload.Size()); err != nil {
+			p.log(ctx).Warn("xz streams unknown", logging.ZapFieldPhase(patcher.PhaseUnpack), zap.Error(err))
+		}
 	case libcpio.HeaderTypeGZ:
-		p.logger.Info(fmt.Sprintf("%s: unpack gz", p.path))
+		p.log(ctx).Info("unpack", logging.ZapFieldPhase(patcher.PhaseUnpack), logging.ZapFieldFormat(img.fileType))
 
-		if err := libio.UnpackGZ(rawFile, inFile, maxDecompressBytes); err != nil {
-			return err
+		if img.streams, err = libio.UnpackGZ(dst, reader, p.limits); err != nil {
+			return err //nolint:wrapcheck
 		}
 	case libcpio.HeaderTypeCPIO, libcpio.HeaderTypeUnknown:
 		return &libcpio.HeaderTypeValueError{
-			Value: fileType.String(),
+			Value: img.fileType.String(),
 		}
 	}
 
-	return nil
+	return ctx.Err() //nolint:wrapcheck
 }
 
-func (p *Patcher) patch(rawFile *os.File, patterns []*patcher.Pattern) (int, error) {
-	var replaced int
+// patch applies patterns to the raw archive and returns the patched bytes
+// count with the offsets replaced by every pattern.
+func (p *Patcher) patch(
+	ctx context.Context,
+	rawFile libio.File,
+	patterns []*patcher.Pattern,
+) (int, []patcher.PatternResult, error) {
+	var (
+		replaced int
+		results  = make([]patcher.PatternResult, 0, len(patterns))
+	)
 
 	for patternIndex, pattern := range patterns {
-		p.logger.Info(fmt.Sprintf("%s: search %d [%s]", p.path, patternIndex, pattern.Description))
-
-		if _, err := rawFile.Seek(0, 0); err != nil {
-			return 0, fmt.Errorf("raw seek failed: %w", err)
-		}
-
-		offsets, err := patcher.SearchBytes(rawFile, pattern.Search, bufferSize, pattern.Count)
+		p.log(ctx).Info(
+			"search",
+			logging.ZapFieldPhase(patcher.PhaseSearch),
+			logging.ZapFieldPattern(patternIndex),
+			logging.ZapFieldDescription(pattern.Description),
+		)
+
+		offsets, err := p.search(ctx, rawFile, patternIndex, pattern)
 		if err != nil {
-			return 0, err
+			return 0, nil, err
 		}
 
-		if len(offsets) == 0 {
-			return 0, &PatternNotFoundError{
-				Path:         p.path,
-				PatternIndex: patternIndex,
-			}
+		if err := patcher.CheckOffsets(p.path, patternIndex, pattern, offsets); err != nil {
+			return 0, nil, err
 		}
 
-		if len(offsets) != pattern.Count {
-			return 0, &InvalidOffsetsLengthError{
-				Path:          p.path,
-				PatternIndex:  patternIndex,
-				PatternsCount: pattern.Count,
-				OffsetsLength: len(offsets),
-			}
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err //nolint:wrapcheck
 		}
 
-		p.logger.Info(fmt.Sprintf("%s: patch %d", p.path, patternIndex))
+		p.log(ctx).Info(
+			"patch",
+			logging.ZapFieldPhase(patcher.PhasePatch),
+			logging.ZapFieldPattern(patternIndex),
+			logging.ZapFieldDescription(pattern.Description),
+			logging.ZapFieldOffsets(offsets),
+		)
+		p.report(patcher.PhasePatch, patternIndex, 0, int64(len(offsets)))
 
-		rbs, err := patcher.ReplaceBytes(rawFile, offsets, pattern.Replace)
+		rbs, err := patcher.ReplaceBytesMask(rawFile, offsets, pattern.Replace, pattern.ReplaceMask)
 		if err != nil {
-			return 0, err
+			return 0, nil, err
 		}
 
 		replaced += rbs
+		results = append(results, patcher.PatternResult{
+			Index:       patternIndex,
+			Description: pattern.Description,
+			Offsets:     offsets,
+		})
 	}
 
-	return replaced, nil
+	return replaced, results, nil
 }
 
-func (p *Patcher) pack(rawFile, inFile, cpioFile *os.File, backup bool) error {
-	if backup {
-		if err := p.backup(inFile); err != nil {
-			return err
-		}
+func (p *Patcher) search(
+	ctx context.Context,
+	rawFile libio.File,
+	patternIndex int,
+	pattern *patcher.Pattern,
+) ([]int64, error) {
+	total, err := remainingSize(rawFile, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := rawFile.Seek(0, 0); err != nil {
+		return nil, fmt.Errorf("raw seek failed: %w", err)
+	}
+
+	offsets, err := patcher.SearchBytesMask(
+		p.reader(ctx, rawFile, patcher.PhaseSearch, patternIndex, total),
+		pattern.Search,
+		pattern.SearchMask,
+		p.bufferSize,
+		pattern.Count,
+	)
+	if err != nil {
+		return nil, err
 	}
 
+	if len(pattern.Member) > 0 {
+		return p.memberOffsets(rawFile, pattern, offsets)
+	}
+
+	if pattern.ELF != nil {
+		return nil, fmt.Errorf("%s: pattern %d: %w", p.path, patternIndex, patcher.ErrPatternELFMemberRequired)
+	}
+
+	return offsets, nil
+}
+
+// memberOffsets drops the offsets which are not fully inside the data of the
+// pattern member, or outside the ELF anchor of the pattern inside the member.
+func (p *Patcher) memberOffsets(rawFile libio.File, pattern *patcher.Pattern, offsets []int64) ([]int64, error) {
 	if _, err := rawFile.Seek(0, 0); err != nil {
+		return nil, fmt.Errorf("raw seek failed: %w", err)
+	}
+
+	start, size, err := libcpio.FindMember(bufio.NewReaderSize(rawFile, p.bufferSize), pattern.Member)
+	if err != nil {
+		return nil, err
+	}
+
+	end := start + size - int64(len(pattern.Search))
+	filtered := offsets[:0]
+
+	for _, offset := range offsets {
+		if offset >= start && offset <= end {
+			filtered = append(filtered, offset-start)
+		}
+	}
+
+	if pattern.ELF != nil {
+		if filtered, err = libelf.FilterOffsets(
+			io.NewSectionReader(rawFile, start, size),
+			pattern.ELF,
+			filtered,
+			len(pattern.Search),
+		); err != nil {
+			return nil, fmt.Errorf("%s: member %s: %w", p.path, pattern.Member, err)
+		}
+	}
+
+	for ind := range filtered {
+		filtered[ind] += start
+	}
+
+	return filtered, nil
+}
+
+// pack compresses the patched image into a temp file first, so a canceled
+// run never touches the input. Only the final commit of the output over the
+// input file is not interruptible.
+func (p *Patcher) pack(ctx context.Context, img *image, backup bool) error {
+	total, err := remainingSize(img.rawFile, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := img.rawFile.Seek(0, 0); err != nil {
 		return fmt.Errorf("raw file seek failed: %w", err)
 	}
 
-	if _, err := inFile.Seek(0, 0); err != nil {
-		return fmt.Errorf("in file seek failed: %w", err)
+	outFile, err := p.packTemp(ctx, img, p.reader(ctx, img.rawFile, patcher.PhasePack, 0, total))
+	if err != nil {
+		return err
+	}
+
+	defer outFile.Close()
+
+	return p.finish(ctx, img, outFile, backup)
+}
+
+// packTemp writes the cpio header and the raw archive read from raw
+// compressed with the output compression into a temp file.
+func (p *Patcher) packTemp(ctx context.Context, img *image, raw io.Reader) (libio.File, error) {
+	outFile, err := p.createTemp("out")
+	if err != nil {
+		return nil, fmt.Errorf("create out file failed: %w", err)
 	}
 
-	if err := inFile.Truncate(0); err != nil {
-		return fmt.Errorf("in file truncate failed: %w", err)
+	if err := p.writeOut(ctx, img, outFile, raw); err != nil {
+		outFile.Close()
+
+		return nil, err
 	}
 
-	if cpioFile != nil {
-		if _, err := cpioFile.Seek(0, 0); err != nil {
+	return outFile, nil
+}
+
+// writeOut writes the cpio header, the raw archive compressed with the output
+// compression and the padding of the compressed payload restored by its
+// policy.
+func (p *Patcher) writeOut(ctx context.Context, img *image, outFile io.WriteSeeker, raw io.Reader) error {
+	if img.cpioFile != nil {
+		if _, err := img.cpioFile.Seek(0, 0); err != nil {
 			return fmt.Errorf("cpio file seek failed: %w", err)
 		}
 
-		if err := libcpio.WriteHeader(inFile, cpioFile, p.cpioZeroFooterSize); err != nil {
+		if err := libcpio.WriteHeader(outFile, img.cpioFile, img.cpioZeroFooterSize); err != nil {
 			return err
 		}
 	}
 
-	p.logger.Info(fmt.Sprintf("%s: pack gz", p.path))
+	if err := p.packPayload(ctx, img, outFile, raw); err != nil {
+		return err
+	}
+
+	end, err := outFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("out file seek failed: %w", err)
+	}
+
+	payload := img.payload()
+
+	padding := payload.RestorePadding(end, p.paddingPols[payload.Name])
+	if padding == 0 {
+		return nil
+	}
+
+	p.log(ctx).Info("pad", logging.ZapFieldPhase(patcher.PhasePack), logging.ZapFieldBytes(padding))
+
+	if _, err := outFile.Write(make([]byte, padding)); err != nil {
+		return fmt.Errorf("out file padding failed: %w", err)
+	}
+
+	return nil
+}
+
+// packPayload compresses raw with the output compression, the padding of
+// the last stream is left to writeOut.
+func (p *Patcher) packPayload(ctx context.Context, img *image, outFile io.Writer, raw io.Reader) error {
+	var streams []libio.Stream
+
+	compression := p.outputCompression(img)
+
+	if p.preserveStreams && len(img.streams) > 1 {
+		p.log(ctx).Info("preserve streams", logging.ZapFieldPhase(patcher.PhasePack), logging.ZapFieldCount(len(img.streams)))
+
+		streams = slices.Clone(img.streams)
+		streams[len(streams)-1].Padding = 0
+	}
+
+	p.log(ctx).Info("pack", logging.ZapFieldPhase(patcher.PhasePack), logging.ZapFieldFormat(compression))
+
+	if compression == libcpio.HeaderTypeXZ {
+		return libio.PackXZStreams(outFile, raw, streams)
+	}
+
+	return libio.PackGZStreams(outFile, raw, streams)
+}
+
+// outputCompression returns the compression of the repacked payload, the
+// input one when its streams are preserved.
+func (p *Patcher) outputCompression(img *image) libcpio.HeaderTypeEnum {
+	if p.preserveStreams && len(img.streams) > 1 {
+		return img.fileType
+	}
+
+	return p.compression
+}
+
+// finish replaces the input with the packed output unless ctx is done.
+func (p *Patcher) finish(ctx context.Context, img *image, outFile libio.File, backup bool) error {
+	if err := ctx.Err(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := p.checkOutputSize(outFile); err != nil {
+		return err
+	}
+
+	if err := img.releaseInput(); err != nil {
+		return err
+	}
+
+	sigFile, err := p.signImage(ctx, outFile)
+	if err != nil {
+		return err
+	}
+
+	if sigFile != nil {
+		defer sigFile.Remove() //nolint:errcheck
+	}
+
+	if backup {
+		if img.backupPath, err = p.backup(ctx, img.inFile); err != nil {
+			return err
+		}
+	}
+
+	output, err := p.commit(ctx, outFile)
+	if err != nil {
+		return err
+	}
+
+	output.Compression = p.outputCompression(img).String()
+	img.output = &output
+
+	if sigFile == nil {
+		return nil
+	}
+
+	if err := sigFile.Commit(); err != nil {
+		return fmt.Errorf("signature commit failed: %w", err)
+	}
+
+	img.signaturePath = p.path + signatureExt
+
+	return nil
+}
+
+// commit copies the packed output into a work file next to the input, then
+// renames it over the input and returns its size and hash. The input is never
+// written, so a failed or interrupted commit leaves it intact.
+func (p *Patcher) commit(ctx context.Context, outFile io.ReadSeeker) (patcher.FileInfo, error) {
+	if _, err := outFile.Seek(0, io.SeekStart); err != nil {
+		return patcher.FileInfo{}, fmt.Errorf("out file seek failed: %w", err)
+	}
+
+	workFile, err := patcher.CreateWorkFile(p.fs, p.path, "")
+	if err != nil {
+		return patcher.FileInfo{}, err
+	}
+
+	defer func() {
+		if err := workFile.Remove(); err != nil {
+			p.log(ctx).Warn("remove work file failed", zap.String("file", workFile.Name()), zap.Error(err))
+		}
+	}()
+
+	info, err := patcher.HashReader(io.TeeReader(outFile, workFile))
+	if err != nil {
+		return patcher.FileInfo{}, fmt.Errorf("work file copy failed: %w", err)
+	}
+
+	if err := workFile.Commit(); err != nil {
+		return patcher.FileInfo{}, err
+	}
+
+	return info, nil
+}
+
+func remainingSize(seeker io.Seeker, offset int64) (int64, error) {
+	size, err := libio.Size(seeker)
+	if err != nil {
+		return 0, err
+	}
 
-	return libio.PackGZ(inFile, rawFile)
+	return size - offset, nil
 }
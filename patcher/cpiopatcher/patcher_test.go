@@ -0,0 +1,849 @@
+package cpiopatcher_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/librsa"
+	"github.com/grinderz/grgo/logging"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher"
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+)
+
+const (
+	testImage     = "initrd.img"
+	testHeaderLen = 512
+	testTempDir   = "tmp"
+)
+
+var errDiskFull = errors.New("disk full")
+
+func testPatterns() []*patcher.Pattern {
+	return []*patcher.Pattern{{
+		Description: "hello",
+		Count:       2,
+		Search:      []byte("HELLO_WORLD"),
+		Replace:     []byte("HELLO_THERE"),
+		Member:      "etc/a.txt",
+	}}
+}
+
+// streamPatterns are the test patterns without the member, as streaming
+// requires.
+func streamPatterns() []*patcher.Pattern {
+	patterns := testPatterns()
+	patterns[0].Member = ""
+
+	return patterns
+}
+
+// readImage returns the content of the test image, its payload is xz.
+func readImage(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + testImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// packImage returns the test image with its payload repacked by pack with
+// the given streams.
+func packImage(
+	t *testing.T,
+	original []byte,
+	pack func(io.Writer, io.Reader, []libio.Stream) error,
+	streams []libio.Stream,
+) []byte {
+	t.Helper()
+
+	var raw, packed bytes.Buffer
+
+	if err := libio.UnpackXZ(&raw, bytes.NewReader(original[testHeaderLen:]), libio.UnpackLimits{}); err != nil {
+		t.Fatal(err)
+	}
+
+	packed.Write(original[:testHeaderLen])
+
+	if err := pack(&packed, &raw, streams); err != nil {
+		t.Fatal(err)
+	}
+
+	return packed.Bytes()
+}
+
+// testSetup describes the filesystem and the patcher of a test run.
+type testSetup struct {
+	// image is the content of the patched image, the test image when nil.
+	image []byte
+	// files are written next to the image.
+	files map[string][]byte
+	// wrap returns the filesystem seen by the patcher, the memory one when nil.
+	wrap func(fsys *libfs.MemFS) libfs.FS
+	opts []cpiopatcher.Option
+}
+
+// setup writes the image and files of s into a memory filesystem and
+// returns it with the image content and a patcher of the image sending its
+// results to the returned channel.
+func setup(t *testing.T, s testSetup) (*libfs.MemFS, []byte, *cpiopatcher.Patcher, <-chan patcher.Result) {
+	t.Helper()
+
+	image := s.image
+	if image == nil {
+		image = readImage(t)
+	}
+
+	fsys := libfs.NewMemFS()
+	fsys.WriteFile(testImage, image)
+
+	for name, data := range s.files {
+		fsys.WriteFile(name, data)
+	}
+
+	var patcherFS libfs.FS = fsys
+	if s.wrap != nil {
+		patcherFS = s.wrap(fsys)
+	}
+
+	result := make(chan patcher.Result, 1)
+	opts := append([]cpiopatcher.Option{
+		cpiopatcher.WithTempDir(testTempDir),
+		cpiopatcher.WithResult(result),
+		cpiopatcher.WithLogger(zap.NewNop()),
+		cpiopatcher.WithFS(patcherFS),
+	}, s.opts...)
+
+	return fsys, image, cpiopatcher.NewPatcher(testImage, opts...), result
+}
+
+// options returns a TestPatchFailed options builder ignoring the cancel
+// function of the run.
+func options(opts ...cpiopatcher.Option) func(context.CancelFunc) []cpiopatcher.Option {
+	return func(context.CancelFunc) []cpiopatcher.Option {
+		return opts
+	}
+}
+
+// checkSent fails unless sent is the result returned by the run.
+func checkSent(t *testing.T, sent <-chan patcher.Result, res patcher.Result) {
+	t.Helper()
+
+	if got := <-sent; got.Path != res.Path || got.BytesPatched != res.BytesPatched || !errors.Is(got.Err, res.Err) {
+		t.Fatalf("sent result non valid: %+v, returned %+v", got, res)
+	}
+}
+
+// readPatched returns the patched image with its payload.
+func readPatched(t *testing.T, fsys *libfs.MemFS, res patcher.Result) ([]byte, []byte) {
+	t.Helper()
+
+	patched, err := fsys.ReadFile(testImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return patched, patched[res.Segments[len(res.Segments)-1].Offset:]
+}
+
+// checkPatched fails unless the image keeps the cpio header of original and
+// its gzip payload holds the replaced test pattern.
+func checkPatched(t *testing.T, fsys *libfs.MemFS, original []byte) {
+	t.Helper()
+
+	patched, err := fsys.ReadFile(testImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(patched[:testHeaderLen], original[:testHeaderLen]) {
+		t.Fatal("cpio header changed")
+	}
+
+	gzReader, err := gzip.NewReader(bytes.NewReader(patched[testHeaderLen:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := io.ReadAll(gzReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Count(string(raw), "HELLO_THERE") != 2 || strings.Contains(string(raw), "HELLO_WORLD") {
+		t.Fatalf("raw not patched: %q", raw)
+	}
+}
+
+// outFailFS fails every write to the repacked image temp file.
+type outFailFS struct {
+	*libfs.MemFS
+}
+
+func (f outFailFS) OpenFile(name string, flag int, perm os.FileMode) (libfs.File, error) {
+	file, err := f.MemFS.OpenFile(name, flag, perm)
+	if err != nil || !strings.HasSuffix(name, ".out") {
+		return file, err
+	}
+
+	return outFailFile{File: file}, nil
+}
+
+type outFailFile struct {
+	libfs.File
+}
+
+func (outFailFile) Write([]byte) (int, error) {
+	return 0, errDiskFull
+}
+
+// readAtFailFS fails every positioned read of the image, sequential reads
+// keep working.
+type readAtFailFS struct {
+	*libfs.MemFS
+}
+
+func (f readAtFailFS) OpenFile(name string, flag int, perm os.FileMode) (libfs.File, error) {
+	file, err := f.MemFS.OpenFile(name, flag, perm)
+	if err != nil || name != testImage {
+		return file, err
+	}
+
+	return readAtFailFile{File: file}, nil
+}
+
+type readAtFailFile struct {
+	libfs.File
+}
+
+func (readAtFailFile) ReadAt([]byte, int64) (int, error) {
+	return 0, errDiskFull
+}
+
+func newImageSigner(t *testing.T) (*librsa.Signer, *librsa.Verifier) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &librsa.Signer{Key: key, Hash: crypto.SHA256, Scheme: librsa.SchemePSS},
+		&librsa.Verifier{Key: &key.PublicKey, Hash: crypto.SHA256, Scheme: librsa.SchemePSS}
+}
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+
+	original := readImage(t)
+	originalSum := sha512.Sum512(original)
+
+	signer, verifier := newImageSigner(t)
+
+	signature, err := signer.Sign(bytes.NewReader(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gzImage := packImage(t, original, libio.PackGZStreams, nil)
+	padding := 2*patcher.MaxPaddingAlign - int64(len(gzImage))%patcher.MaxPaddingAlign
+	paddedImage := append(slices.Clone(gzImage), make([]byte, padding)...)
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		setup    testSetup
+		opts     []cpiopatcher.Option
+		patterns []*patcher.Pattern
+		backup   bool
+		check    func(t *testing.T, fsys *libfs.MemFS, res patcher.Result)
+	}{
+		{
+			name:     "memory storage with backup",
+			opts:     []cpiopatcher.Option{cpiopatcher.WithStorage(cpiopatcher.StorageModeMemory, 0)},
+			patterns: testPatterns(),
+			backup:   true,
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				if res.Backup != testImage+".bak" || len(res.Patterns) != 1 || len(res.Patterns[0].Offsets) != 2 {
+					t.Fatalf("result non valid: %+v", res)
+				}
+
+				if res.Input.Compression != "xz" || res.Output == nil || res.Output.Compression != "gz" {
+					t.Fatalf("result compression non valid: %+v", res)
+				}
+
+				if res.Input.Size != int64(len(original)) || res.Input.SHA512 != hex.EncodeToString(originalSum[:]) {
+					t.Fatalf("input info non valid: %+v", res.Input)
+				}
+
+				if backup, err := fsys.ReadFile(testImage + ".bak"); err != nil || !bytes.Equal(backup, original) {
+					t.Fatalf("backup differs from original: %v", err)
+				}
+
+				checkPatched(t, fsys, original)
+			},
+		},
+		{
+			name:     "streaming",
+			opts:     []cpiopatcher.Option{cpiopatcher.WithStreaming(true)},
+			patterns: streamPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, _ patcher.Result) {
+				checkPatched(t, fsys, original)
+			},
+		},
+		{
+			name: "expected digest",
+			opts: []cpiopatcher.Option{
+				cpiopatcher.WithExpectedDigest(patcher.Digest{Algorithm: patcher.DigestSHA512, Sum: hex.EncodeToString(originalSum[:])}),
+			},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				patched, _ := readPatched(t, fsys, res)
+
+				if sum := sha512.Sum512(patched); res.Output == nil || res.Output.SHA512 != hex.EncodeToString(sum[:]) {
+					t.Fatalf("output digest non valid: %+v", res.Output)
+				}
+
+				if res.Input.SHA512 != hex.EncodeToString(originalSum[:]) {
+					t.Fatalf("input digest non valid: %+v", res.Input)
+				}
+			},
+		},
+		{
+			name:     "image signature",
+			setup:    testSetup{files: map[string][]byte{testImage + ".sig": signature}},
+			opts:     []cpiopatcher.Option{cpiopatcher.WithImageSignature(signer, verifier)},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				patched, _ := readPatched(t, fsys, res)
+
+				signature, err := fsys.ReadFile(res.SignaturePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := verifier.Verify(bytes.NewReader(patched), signature); err != nil {
+					t.Fatalf("patched image signature non valid: %v", err)
+				}
+			},
+		},
+		{
+			name:     "preserve gz streams",
+			setup:    testSetup{image: packImage(t, original, libio.PackGZStreams, []libio.Stream{{RawSize: 200}, {}})},
+			opts:     []cpiopatcher.Option{cpiopatcher.WithPreserveStreams(true)},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				segment := res.Segments[len(res.Segments)-1]
+				if len(segment.Streams) != 2 || segment.Streams[0].RawSize != 200 || segment.Streams[1].Offset != segment.Streams[0].Size {
+					t.Fatalf("payload streams non valid: %+v", segment)
+				}
+
+				_, payload := readPatched(t, fsys, res)
+
+				streams, err := libio.UnpackGZ(io.Discard, bytes.NewReader(payload), libio.UnpackLimits{})
+				if err != nil || len(streams) != 2 || streams[0].RawSize != 200 || streams[1].RawSize != segment.Streams[1].RawSize {
+					t.Fatalf("patched streams non valid: %+v %v", streams, err)
+				}
+			},
+		},
+		{
+			name:     "preserve xz streams",
+			setup:    testSetup{image: packImage(t, original, libio.PackXZStreams, []libio.Stream{{RawSize: 200}, {Padding: 8}})},
+			opts:     []cpiopatcher.Option{cpiopatcher.WithPreserveStreams(true)},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				if res.Output == nil || res.Output.Compression != "xz" {
+					t.Fatalf("output compression non valid: %+v", res.Output)
+				}
+
+				segment := res.Segments[len(res.Segments)-1]
+				_, payload := readPatched(t, fsys, res)
+
+				streams, err := libio.XZStreams(bytes.NewReader(payload), int64(len(payload)))
+				if err != nil || len(streams) != 2 || streams[0].RawSize != 200 || streams[1].RawSize != segment.Streams[1].RawSize {
+					t.Fatalf("patched streams non valid: %+v %v", streams, err)
+				}
+			},
+		},
+		{
+			name:     "xz streams unknown",
+			setup:    testSetup{wrap: func(fsys *libfs.MemFS) libfs.FS { return readAtFailFS{MemFS: fsys} }},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				if segment := res.Segments[len(res.Segments)-1]; len(segment.Streams) > 0 {
+					t.Fatalf("payload streams non valid: %+v", segment)
+				}
+
+				checkPatched(t, fsys, original)
+			},
+		},
+		{
+			name:     "padding align",
+			setup:    testSetup{image: paddedImage},
+			opts:     []cpiopatcher.Option{cpiopatcher.WithPaddingPolicy("gz", patcher.PaddingPolicyAlign)},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				if patched, _ := readPatched(t, fsys, res); len(patched)%patcher.MaxPaddingAlign != 0 {
+					t.Fatalf("output alignment non valid: %d", len(patched))
+				}
+			},
+		},
+		{
+			name:     "padding keep",
+			setup:    testSetup{image: paddedImage},
+			opts:     []cpiopatcher.Option{cpiopatcher.WithPaddingPolicy("gz", patcher.PaddingPolicyKeep)},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				checkPadding(t, fsys, res, padding, padding)
+			},
+		},
+		{
+			name:     "padding drop",
+			setup:    testSetup{image: paddedImage},
+			opts:     []cpiopatcher.Option{cpiopatcher.WithPaddingPolicy("gz", patcher.PaddingPolicyDrop)},
+			patterns: testPatterns(),
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				checkPadding(t, fsys, res, padding, 0)
+			},
+		},
+		{
+			name: "options",
+			setup: testSetup{opts: []cpiopatcher.Option{
+				cpiopatcher.WithBufferSize(64),
+				cpiopatcher.WithFilePerm(0o600),
+				cpiopatcher.WithCompression(libcpio.HeaderTypeXZ),
+				cpiopatcher.WithBackupPolicy(patcher.BackupPolicy{Timestamp: true}),
+				cpiopatcher.WithClock(func() time.Time { return now }),
+			}},
+			patterns: testPatterns(),
+			backup:   true,
+			check: func(t *testing.T, fsys *libfs.MemFS, res patcher.Result) {
+				if res.Output == nil || res.Output.Compression != "xz" || res.Backup != testImage+".20240506T070809Z.bak" {
+					t.Fatalf("result non valid: %+v", res)
+				}
+
+				_, payload := readPatched(t, fsys, res)
+
+				if err := libio.UnpackXZ(io.Discard, bytes.NewReader(payload), libio.UnpackLimits{}); err != nil {
+					t.Fatalf("xz output non valid: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			test.setup.opts = append(test.setup.opts, test.opts...)
+			fsys, _, p, sent := setup(t, test.setup)
+
+			res, err := p.Patch(test.patterns, test.backup)
+			if err != nil || res.Err != nil || res.BytesPatched != 22 || res.Path != testImage {
+				t.Fatalf("result non valid: %+v %v", res, err)
+			}
+
+			checkSent(t, sent, res)
+
+			for _, name := range fsys.Names() {
+				if strings.HasPrefix(name, testTempDir+"/") || strings.HasPrefix(name, ".") {
+					t.Fatalf("temp file left: %s", name)
+				}
+			}
+
+			test.check(t, fsys, res)
+		})
+	}
+}
+
+// checkPadding fails unless the payload segment recorded padding and the
+// patched payload is followed by want zero bytes.
+func checkPadding(t *testing.T, fsys *libfs.MemFS, res patcher.Result, padding, want int64) {
+	t.Helper()
+
+	if segment := res.Segments[len(res.Segments)-1]; segment.Padding != padding || segment.Align != patcher.MaxPaddingAlign {
+		t.Fatalf("payload segment non valid: %+v", segment)
+	}
+
+	_, payload := readPatched(t, fsys, res)
+
+	streams, err := libio.UnpackGZ(io.Discard, bytes.NewReader(payload), libio.UnpackLimits{})
+	if err != nil || streams[0].Padding != want {
+		t.Fatalf("output padding non valid: %+v %v", streams, err)
+	}
+}
+
+func TestPatchFailed(t *testing.T) {
+	t.Parallel()
+
+	original := readImage(t)
+	signer, verifier := newImageSigner(t)
+
+	missing := testPatterns()
+	missing[0].Member = "etc/missing.txt"
+
+	tests := []struct {
+		name     string
+		setup    testSetup
+		opts     func(cancel context.CancelFunc) []cpiopatcher.Option
+		patterns []*patcher.Pattern
+		check    func(err error) bool
+	}{
+		{
+			name:     "streaming member",
+			opts:     options(cpiopatcher.WithStreaming(true)),
+			patterns: testPatterns(),
+			check:    func(err error) bool { return errors.Is(err, patcher.ErrPatternStreamUnsupported) },
+		},
+		{
+			name:     "streaming pack",
+			setup:    testSetup{wrap: func(fsys *libfs.MemFS) libfs.FS { return outFailFS{MemFS: fsys} }},
+			opts:     options(cpiopatcher.WithStreaming(true)),
+			patterns: streamPatterns(),
+			check:    func(err error) bool { return errors.Is(err, errDiskFull) },
+		},
+		{
+			name:     "streaming preserve streams",
+			opts:     options(cpiopatcher.WithStreaming(true), cpiopatcher.WithPreserveStreams(true)),
+			patterns: streamPatterns(),
+			check:    func(err error) bool { return errors.Is(err, cpiopatcher.ErrStreamPreserveStreams) },
+		},
+		{
+			name: "canceled",
+			opts: func(cancel context.CancelFunc) []cpiopatcher.Option {
+				return []cpiopatcher.Option{cpiopatcher.WithProgress(func(progress patcher.Progress) {
+					if progress.Phase == patcher.PhaseSearch {
+						cancel()
+					}
+				})}
+			},
+			patterns: testPatterns(),
+			check:    func(err error) bool { return errors.Is(err, context.Canceled) },
+		},
+		{
+			name:     "truncated",
+			setup:    testSetup{image: original[:len(original)-64]},
+			patterns: testPatterns(),
+			check:    liberr.IsCorruptInput,
+		},
+		{
+			name:     "unsupported",
+			setup:    testSetup{image: bytes.Repeat([]byte{0x42}, testHeaderLen)},
+			patterns: testPatterns(),
+			check:    liberr.IsUserError,
+		},
+		{
+			name:     "member",
+			patterns: missing,
+			check:    liberr.IsUserError,
+		},
+		{
+			name: "digest mismatch",
+			opts: options(
+				cpiopatcher.WithExpectedDigest(patcher.Digest{Algorithm: patcher.DigestSHA256, Sum: strings.Repeat("0", 64)}),
+			),
+			patterns: testPatterns(),
+			check: func(err error) bool {
+				var mismatch *patcher.DigestMismatchError
+
+				return errors.As(err, &mismatch)
+			},
+		},
+		{
+			name:     "image signature",
+			setup:    testSetup{files: map[string][]byte{testImage + ".sig": []byte("bogus")}},
+			opts:     options(cpiopatcher.WithImageSignature(signer, verifier)),
+			patterns: testPatterns(),
+			check:    liberr.IsCorruptInput,
+		},
+		{
+			name:  "image signing",
+			setup: testSetup{files: map[string][]byte{testImage + ".sig": []byte("previous")}},
+			opts: options(
+				cpiopatcher.WithImageSignature(&librsa.Signer{Key: signer.Key, Hash: signer.Hash, Scheme: librsa.SchemeUnknown}, nil),
+			),
+			patterns: testPatterns(),
+			check: func(err error) bool {
+				var scheme *librsa.SchemeValueError
+
+				return errors.As(err, &scheme)
+			},
+		},
+		{
+			name:     "max output size",
+			opts:     options(cpiopatcher.WithMaxOutputSize(int64(len(original)) / 2)),
+			patterns: testPatterns(),
+			check: func(err error) bool {
+				var tooLarge *cpiopatcher.OutputTooLargeError
+
+				return errors.As(err, &tooLarge)
+			},
+		},
+		{
+			name:     "compression",
+			setup:    testSetup{opts: []cpiopatcher.Option{cpiopatcher.WithCompression(libcpio.HeaderTypeCPIO)}},
+			patterns: testPatterns(),
+			check:    func(err error) bool { return errors.Is(err, liberr.ErrInvalid) },
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			defer cancel()
+
+			if test.opts != nil {
+				test.setup.opts = append(test.setup.opts, test.opts(cancel)...)
+			}
+
+			fsys, image, p, sent := setup(t, test.setup)
+
+			res, err := p.PatchContext(ctx, test.patterns, true)
+			if !test.check(err) || !errors.Is(res.Err, err) || res.Path != testImage {
+				t.Fatalf("error non valid: %+v %v", res, err)
+			}
+
+			checkSent(t, sent, res)
+
+			if data, _ := fsys.ReadFile(testImage); !bytes.Equal(data, image) {
+				t.Fatal("input changed")
+			}
+
+			for name, content := range test.setup.files {
+				if data, _ := fsys.ReadFile(name); !bytes.Equal(data, content) {
+					t.Fatalf("%s changed", name)
+				}
+			}
+
+			for _, name := range fsys.Names() {
+				if name != testImage && name != testImage+".sig" {
+					t.Fatalf("file left: %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchTempWorkspace(t *testing.T) {
+	t.Parallel()
+
+	missing := testPatterns()
+	missing[0].Member = "etc/missing.txt"
+
+	tests := []struct {
+		name     string
+		patterns []*patcher.Pattern
+		keep     bool
+	}{
+		{"success", testPatterns(), false},
+		{"failure", missing, false},
+		{"keep", testPatterns(), true},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			fsys, _, p, _ := setup(t, testSetup{opts: []cpiopatcher.Option{cpiopatcher.WithKeepTemp(test.keep)}})
+			p.Patch(test.patterns, false)
+
+			var temps []string
+
+			for _, name := range fsys.Names() {
+				if strings.HasPrefix(name, testTempDir+"/") {
+					temps = append(temps, name)
+				}
+			}
+
+			if test.keep != (len(temps) > 0) {
+				t.Fatalf("temp files non valid: %v", temps)
+			}
+		})
+	}
+}
+
+func TestPatchProgress(t *testing.T) {
+	t.Parallel()
+
+	phases := make([]patcher.PhaseEnum, 0)
+	done := make(map[patcher.PhaseEnum]bool)
+
+	progress := cpiopatcher.WithProgress(func(progress patcher.Progress) {
+		if progress.Path != testImage || progress.Total > 0 && progress.Bytes > progress.Total {
+			t.Errorf("progress non valid: %+v", progress)
+		}
+
+		if len(phases) == 0 || phases[len(phases)-1] != progress.Phase {
+			phases = append(phases, progress.Phase)
+		}
+
+		if progress.Total > 0 && progress.Bytes == progress.Total {
+			done[progress.Phase] = true
+		}
+	})
+
+	_, _, p, _ := setup(t, testSetup{opts: []cpiopatcher.Option{progress}})
+	if _, err := p.Patch(testPatterns(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, phase := range []patcher.PhaseEnum{
+		patcher.PhaseCut,
+		patcher.PhaseUnpack,
+		patcher.PhaseSearch,
+		patcher.PhasePatch,
+		patcher.PhaseBackup,
+		patcher.PhasePack,
+	} {
+		if !slices.Contains(phases, phase) {
+			t.Fatalf("phase %s not reported: %v", phase, phases)
+		}
+	}
+
+	if !done[patcher.PhaseUnpack] || !done[patcher.PhaseSearch] {
+		t.Fatalf("phases not completed: %v", done)
+	}
+}
+
+func TestPatchContextLogger(t *testing.T) {
+	t.Parallel()
+
+	fsys, _, _, _ := setup(t, testSetup{})
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := logging.ToContext(context.Background(), zap.New(core))
+
+	p := cpiopatcher.NewPatcher(testImage, cpiopatcher.WithTempDir(testTempDir), cpiopatcher.WithFS(fsys))
+	if _, err := p.PatchContext(ctx, testPatterns(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	patched := logs.FilterMessage("patch").All()
+	if len(patched) != 1 {
+		t.Fatalf("patch log entries non valid: %+v", logs.All())
+	}
+
+	fields := patched[0].ContextMap()
+	if fields["path"] != testImage || fields["pkg"] != "cpiopatcher" || fields["phase"] != "patch" ||
+		fields["pattern"] != int64(0) || fields["description"] != "hello" || fields["offsets"] == nil {
+		t.Fatalf("patch log fields non valid: %+v", fields)
+	}
+
+	if logs.FilterMessage("done").FilterField(logging.ZapFieldBytes(22)).Len() != 1 {
+		t.Fatalf("done log entry non valid: %+v", logs.All())
+	}
+}
+
+func TestPatchOSFS(t *testing.T) {
+	t.Parallel()
+
+	original := readImage(t)
+	padded := packImage(t, original, libio.PackGZStreams, []libio.Stream{{RawSize: 200}, {RawSize: 300}, {Padding: 512}})
+
+	tests := []struct {
+		name    string
+		image   []byte
+		storage cpiopatcher.StorageModeEnum
+	}{
+		{"xz", original, cpiopatcher.StorageModeMemory},
+		{"padded gz members", padded, cpiopatcher.StorageModeFile},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, testImage)
+			link := filepath.Join(dir, "link.img")
+
+			if err := os.WriteFile(path, test.image, 0o640); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Link(path, link); err != nil {
+				t.Skip(err)
+			}
+
+			p := cpiopatcher.NewPatcher(path, cpiopatcher.WithTempDir(t.TempDir()), cpiopatcher.WithStorage(test.storage, 0))
+
+			res, err := p.Patch(testPatterns(), false)
+			if err != nil || res.BytesPatched != 22 {
+				t.Fatalf("result non valid: %+v %v", res, err)
+			}
+
+			if linked, err := os.ReadFile(link); err != nil || !bytes.Equal(linked, test.image) {
+				t.Fatalf("input written in place: %v", err)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Mode().Perm() != 0o640 || info.Size() != res.Output.Size {
+				t.Fatalf("replaced image non valid: %v %d", info.Mode(), info.Size())
+			}
+
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) != 2 {
+				t.Fatalf("work files left: %v %v", entries, err)
+			}
+		})
+	}
+}
+
+func TestPatchSync(t *testing.T) {
+	t.Parallel()
+
+	fsys, original, _, _ := setup(t, testSetup{})
+
+	var p patcher.Patcher = cpiopatcher.NewPatcher(testImage, cpiopatcher.WithFS(fsys))
+
+	res, err := p.Patch(testPatterns(), false)
+	if err != nil || res.Err != nil || res.BytesPatched != 22 {
+		t.Fatalf("result non valid: %+v %v", res, err)
+	}
+
+	checkPatched(t, fsys, original)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res, err = p.PatchContext(ctx, testPatterns(), false)
+	if !errors.Is(err, context.Canceled) || !errors.Is(res.Err, context.Canceled) || res.Path != testImage {
+		t.Fatalf("canceled result non valid: %+v %v", res, err)
+	}
+}
@@ -0,0 +1,188 @@
+package cpiopatcher
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/libos"
+	"github.com/grinderz/grgo/patcher/cpiopatcher/libcpio"
+)
+
+// WithMaxOutputSize fails the run before the input is replaced when the
+// repacked image is larger than size, a non positive size disables it.
+func WithMaxOutputSize(size int64) Option {
+	return func(p *Patcher) {
+		p.maxOutputSize = size
+	}
+}
+
+// preflight estimates the space needed by the run from the uncompressed
+// payload size and fails with InsufficientSpaceError when the temp
+// directory, the image or the backup directory cannot hold it. Output size
+// is estimated as the input size, the space required by directories sharing
+// a filesystem is summed. Only OS filesystems are checked.
+func (p *Patcher) preflight(img *image, backup bool) error {
+	if _, ok := p.fs.(*libfs.OSFS); !ok {
+		return nil
+	}
+
+	required := make(map[string]int64)
+	tempDir := p.tempDir
+
+	if len(tempDir) == 0 {
+		tempDir = os.TempDir()
+	}
+
+	if p.storageMode == StorageModeFile || p.memoryLimit > 0 {
+		required[tempDir] += img.info.Size
+
+		if !p.streaming {
+			free, err := libos.FreeSpace(tempDir)
+			if errors.Is(err, libos.ErrFreeSpaceUnsupported) {
+				return nil
+			}
+
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			raw, err := p.rawSize(img, int64(min(free, math.MaxInt64)))
+			if err != nil {
+				return err
+			}
+
+			required[tempDir] += raw
+		}
+	}
+
+	required[filepath.Dir(p.path)] += img.info.Size
+
+	if backup {
+		required[filepath.Dir(p.backupPol.Path(p.path, time.Time{}))] += img.info.Size
+	}
+
+	dirs := make([]string, 0, len(required))
+	for dir := range required {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+
+	spaces, err := fileSystemSpaces(dirs, required)
+	if errors.Is(err, libos.ErrFreeSpaceUnsupported) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, space := range spaces {
+		free, err := libos.FreeSpace(space.dir)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		if uint64(space.required) > free {
+			return &InsufficientSpaceError{Path: space.dir, Required: space.required, Available: free}
+		}
+	}
+
+	return nil
+}
+
+// fileSystemSpace is the space required on one filesystem, dir is the first
+// directory of the run on it.
+type fileSystemSpace struct {
+	dir      string
+	required int64
+}
+
+// fileSystemSpaces sums the space required by dirs per filesystem, in the
+// order of dirs.
+func fileSystemSpaces(dirs []string, required map[string]int64) ([]*fileSystemSpace, error) {
+	var (
+		spaces []*fileSystemSpace
+		byID   = make(map[uint64]*fileSystemSpace)
+	)
+
+	for _, dir := range dirs {
+		id, err := libos.FileSystemID(dir)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		if space, ok := byID[id]; ok {
+			space.required += required[dir]
+			continue
+		}
+
+		byID[id] = &fileSystemSpace{dir: dir, required: required[dir]}
+		spaces = append(spaces, byID[id])
+	}
+
+	return spaces, nil
+}
+
+// rawSize returns the uncompressed payload size, summed over every gzip
+// member or xz stream, never less than the compressed size. gzip members are
+// inflated under the unpack limits, stopping once more than free bytes are
+// produced: the size is then reported as free, which added to the input size
+// fails the free space check.
+func (p *Patcher) rawSize(img *image, free int64) (int64, error) {
+	pos, err := img.input.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	offset := pos - libcpio.MaxMagicSize
+	payload := io.NewSectionReader(img.inFile, offset, img.info.Size-offset)
+
+	var raw int64
+
+	switch img.fileType {
+	case libcpio.HeaderTypeGZ:
+		limits := p.limits
+		if limits.MaxSize <= 0 || limits.MaxSize > free {
+			limits.MaxSize = free
+		}
+
+		raw, err = libio.GZUncompressedSize(payload, payload.Size(), limits)
+		if errors.Is(err, libio.ErrUnpackMaxDecompressLimitReached) && limits.MaxSize == free {
+			raw, err = free, nil
+		}
+	case libcpio.HeaderTypeXZ:
+		// Indexes which cannot be walked are left to the decoder, the
+		// compressed size is the estimate.
+		if raw, err = libio.XZUncompressedSize(payload, payload.Size()); errors.Is(err, libio.ErrXZIndex) {
+			raw, err = 0, nil
+		}
+	case libcpio.HeaderTypeCPIO, libcpio.HeaderTypeUnknown:
+	}
+
+	return max(raw, payload.Size()), err //nolint:wrapcheck
+}
+
+func (p *Patcher) checkOutputSize(outFile libio.File) error {
+	if p.maxOutputSize <= 0 {
+		return nil
+	}
+
+	size, err := libio.Size(outFile)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if size > p.maxOutputSize {
+		return &OutputTooLargeError{Path: p.path, Size: size, Max: p.maxOutputSize}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package cpiopatcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=StorageModeEnum -linecomment -output storage_mode_enum_string.go
+type StorageModeEnum int
+
+const (
+	StorageModeUnknown StorageModeEnum = iota // unknown
+	StorageModeFile    StorageModeEnum = iota // file
+	StorageModeMemory  StorageModeEnum = iota // memory
+	StorageModeMmap    StorageModeEnum = iota // mmap
+)
+
+func (e *StorageModeEnum) SetValue(value string) error {
+	mode := StorageModeFromString(value)
+	if mode == StorageModeUnknown {
+		return &StorageModeValueError{
+			Value: value,
+		}
+	}
+
+	*e = mode
+
+	return nil
+}
+
+func (e StorageModeEnum) MarshalText() ([]byte, error) {
+	if e == StorageModeUnknown {
+		return nil, &StorageModeValueError{
+			Value: StorageModeUnknown.String(),
+		}
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *StorageModeEnum) UnmarshalText(text []byte) error {
+	return e.SetValue(string(text))
+}
+
+func StorageModeFromString(value string) StorageModeEnum {
+	switch strings.ToLower(value) {
+	case "file":
+		return StorageModeFile
+	case "memory":
+		return StorageModeMemory
+	case "mmap":
+		return StorageModeMmap
+	default:
+		return StorageModeUnknown
+	}
+}
+
+type StorageModeValueError struct {
+	Value string
+}
+
+func (e *StorageModeValueError) Error() string {
+	return fmt.Sprintf("storage mode invalid value: %s", e.Value)
+}
+
+func (e *StorageModeValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=StorageModeEnum -linecomment -output storage_mode_enum_string.go"; DO NOT EDIT.
+
+package cpiopatcher
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[StorageModeUnknown-0]
+	_ = x[StorageModeFile-1]
+	_ = x[StorageModeMemory-2]
+	_ = x[StorageModeMmap-3]
+}
+
+const _StorageModeEnum_name = "unknownfilememorymmap"
+
+var _StorageModeEnum_index = [...]uint8{0, 7, 11, 17, 21}
+
+func (i StorageModeEnum) String() string {
+	if i < 0 || i >= StorageModeEnum(len(_StorageModeEnum_index)-1) {
+		return "StorageModeEnum(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _StorageModeEnum_name[_StorageModeEnum_index[i]:_StorageModeEnum_index[i+1]]
+}
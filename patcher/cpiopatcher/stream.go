@@ -0,0 +1,111 @@
+package cpiopatcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+// WithStreaming makes Patch decompress, replace and compress the archive in a
+// single pass instead of unpacking it into temp storage and rescanning it
+// for every pattern. Memory stays bounded by the longest pattern. Patterns
+// with member or ELF restrictions are rejected and appended module
+// signatures are neither detected nor handled in this mode.
+func WithStreaming(streaming bool) Option {
+	return func(p *Patcher) {
+		p.streaming = streaming
+	}
+}
+
+func (p *Patcher) stream(
+	ctx context.Context,
+	patterns []*patcher.Pattern,
+	backup, force bool,
+) (patcher.Result, error) {
+	if p.sigPolicy != patcher.SignaturePolicyKeep {
+		return patcher.Result{}, ErrStreamSignaturePolicy
+	}
+
+	if p.preserveStreams {
+		return patcher.Result{}, ErrStreamPreserveStreams
+	}
+
+	img, err := p.openInput(ctx, os.O_RDONLY)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	defer img.Close()
+
+	if err := p.preflight(img, backup); err != nil {
+		return patcher.Result{}, err
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+
+	replacer, err := patcher.NewReplaceReader(pipeReader, patterns, p.bufferSize)
+	if err != nil {
+		pipeWriter.Close()
+		pipeReader.Close()
+
+		return patcher.Result{}, err //nolint:wrapcheck
+	}
+
+	unpacked := make(chan error, 1)
+
+	go func() {
+		err := p.unpack(ctx, img, pipeWriter)
+		pipeWriter.CloseWithError(err)
+		unpacked <- err
+	}()
+
+	p.log(ctx).Info("stream patch")
+
+	outFile, err := p.packTemp(ctx, img, replacer)
+	pipeReader.Close()
+
+	// A failed pack closes the pipe under the unpacker, its closed pipe error
+	// would hide the cause.
+	if unpackErr := <-unpacked; err == nil && !errors.Is(unpackErr, io.ErrClosedPipe) {
+		err = unpackErr
+	}
+
+	if err != nil {
+		if outFile != nil {
+			outFile.Close()
+		}
+
+		return patcher.Result{}, err
+	}
+
+	defer outFile.Close()
+
+	patternResults := make([]patcher.PatternResult, 0, len(patterns))
+
+	for patternIndex, pattern := range patterns {
+		offsets := replacer.Offsets(patternIndex)
+		if err := patcher.CheckOffsets(p.path, patternIndex, pattern, offsets); err != nil {
+			return patcher.Result{}, err //nolint:wrapcheck
+		}
+
+		patternResults = append(patternResults, patcher.PatternResult{
+			Index:       patternIndex,
+			Description: pattern.Description,
+			Offsets:     offsets,
+		})
+	}
+
+	result, err := p.newResult(img, replacer.Replaced(), patternResults)
+	if err != nil || (result.BytesPatched == 0 && !force) {
+		return result, err
+	}
+
+	if err := p.finish(ctx, img, outFile, backup); err != nil {
+		return patcher.Result{}, err
+	}
+
+	return p.completeResult(img, result), nil
+}
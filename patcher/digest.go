@@ -0,0 +1,135 @@
+package patcher
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+const (
+	DigestSHA256 = "sha256"
+	DigestSHA512 = "sha512"
+)
+
+// Digest is an expected file hash written as "sha256:<hex>" or
+// "sha512:<hex>", a bare hex value selects the algorithm by its length.
+// The zero Digest matches every file.
+type Digest struct {
+	Algorithm string
+	Sum       string
+}
+
+func ParseDigest(value string) (Digest, error) {
+	algorithm, sum, found := strings.Cut(strings.TrimSpace(value), ":")
+	if !found {
+		sum = algorithm
+
+		switch len(sum) {
+		case hex.EncodedLen(sha256.Size):
+			algorithm = DigestSHA256
+		case hex.EncodedLen(sha512.Size):
+			algorithm = DigestSHA512
+		}
+	}
+
+	digest := Digest{Algorithm: strings.ToLower(algorithm), Sum: strings.ToLower(sum)}
+
+	if _, err := hex.DecodeString(digest.Sum); err != nil || len(digest.Sum) != digest.sumLen() {
+		return Digest{}, &DigestValueError{Value: value}
+	}
+
+	return digest, nil
+}
+
+func (d Digest) sumLen() int {
+	switch d.Algorithm {
+	case DigestSHA256:
+		return hex.EncodedLen(sha256.Size)
+	case DigestSHA512:
+		return hex.EncodedLen(sha512.Size)
+	default:
+		return -1
+	}
+}
+
+func (d Digest) IsZero() bool {
+	return len(d.Sum) == 0
+}
+
+func (d Digest) String() string {
+	if d.IsZero() {
+		return ""
+	}
+
+	return d.Algorithm + ":" + d.Sum
+}
+
+func (d Digest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Digest) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = Digest{}
+
+		return nil
+	}
+
+	digest, err := ParseDigest(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = digest
+
+	return nil
+}
+
+// Verify fails with DigestMismatchError when info does not hash to d.
+func (d Digest) Verify(info FileInfo) error {
+	if d.IsZero() {
+		return nil
+	}
+
+	actual := info.SHA256
+	if d.Algorithm == DigestSHA512 {
+		actual = info.SHA512
+	}
+
+	if subtle.ConstantTimeCompare([]byte(actual), []byte(d.Sum)) != 1 {
+		return &DigestMismatchError{Algorithm: d.Algorithm, Expected: d.Sum, Actual: actual}
+	}
+
+	return nil
+}
+
+type DigestValueError struct {
+	Value string
+}
+
+func (e *DigestValueError) Error() string {
+	return fmt.Sprintf("digest invalid value: %s", e.Value)
+}
+
+func (e *DigestValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type DigestMismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("%s digest mismatch: expected %s, got %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+func (e *DigestMismatchError) Unwrap() error {
+	return liberr.ErrInvalid
+}
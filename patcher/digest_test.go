@@ -0,0 +1,57 @@
+package patcher_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestParseDigest(t *testing.T) {
+	t.Parallel()
+
+	sha256Sum := strings.Repeat("ab", 32)
+	sha512Sum := strings.Repeat("cd", 64)
+
+	tests := []struct {
+		value     string
+		algorithm string
+		valid     bool
+	}{
+		{"sha256:" + sha256Sum, patcher.DigestSHA256, true},
+		{"SHA512:" + strings.ToUpper(sha512Sum), patcher.DigestSHA512, true},
+		{sha256Sum, patcher.DigestSHA256, true},
+		{sha512Sum, patcher.DigestSHA512, true},
+		{"sha512:" + sha256Sum, "", false},
+		{"md5:" + sha256Sum, "", false},
+		{"sha256:" + strings.Repeat("zz", 32), "", false},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+
+			digest, err := patcher.ParseDigest(test.value)
+
+			var valueErr *patcher.DigestValueError
+			if !test.valid {
+				if !errors.As(err, &valueErr) {
+					t.Fatalf("expected value error, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if digest.Algorithm != test.algorithm || digest.Sum != strings.ToLower(digest.Sum) {
+				t.Fatalf("digest non valid: %+v", digest)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package patcher
+
+import (
+	"fmt"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+var (
+	// ErrBatchSkipped is an I/O class error as the skipped job never ran, so
+	// running it again may succeed.
+	ErrBatchSkipped             = liberr.New(liberr.ErrIO, "batch job skipped")
+	ErrPatternMemberUnsupported = liberr.New(liberr.ErrInvalid, "pattern member not supported by patcher")
+	ErrPatternELFMemberRequired = liberr.New(liberr.ErrInvalid, "pattern elf anchor requires a member")
+	ErrModuleSignerRequired     = liberr.New(liberr.ErrInvalid, "module signer required by resign policy")
+	ErrPatternStreamUnsupported = liberr.New(liberr.ErrInvalid, "pattern member and elf anchor not supported by streaming")
+
+	ErrPatternSetSyntax          = liberr.New(liberr.ErrInvalid, "pattern set syntax error")
+	ErrPatternSetFormat          = liberr.New(liberr.ErrInvalid, "pattern set unsupported format")
+	ErrPatternSetEmpty           = liberr.New(liberr.ErrInvalid, "pattern set empty value")
+	ErrPatternSetType            = liberr.New(liberr.ErrInvalid, "pattern set invalid type")
+	ErrPatternSetUnknownField    = liberr.New(liberr.ErrInvalid, "pattern set unknown field")
+	ErrPatternSetDuplicateField  = liberr.New(liberr.ErrInvalid, "pattern set duplicate field")
+	ErrPatternSetFieldRequired   = liberr.New(liberr.ErrInvalid, "pattern set field required")
+	ErrPatternSetCount           = liberr.New(liberr.ErrInvalid, "pattern set count must be positive")
+	ErrPatternSetHex             = liberr.New(liberr.ErrInvalid, "pattern set invalid hex")
+	ErrPatternSetLength          = liberr.New(liberr.ErrInvalid, "pattern set search and replace length mismatch")
+	ErrPatternSetVersionRequired = liberr.New(liberr.ErrInvalid, "pattern set version required by constraints")
+)
+
+type InvalidOffsetsLengthError struct {
+	Path          string
+	PatternIndex  int
+	PatternsCount int
+	OffsetsLength int
+}
+
+func (e *InvalidOffsetsLengthError) Error() string {
+	return fmt.Sprintf(
+		"%s: pattern %d invalid offsets length offsets_len[%d] != pattern_count[%d]",
+		e.Path,
+		e.PatternIndex,
+		e.OffsetsLength,
+		e.PatternsCount,
+	)
+}
+
+func (e *InvalidOffsetsLengthError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type PatternNotFoundError struct {
+	Path         string
+	PatternIndex int
+}
+
+func (e *PatternNotFoundError) Error() string {
+	return fmt.Sprintf(
+		"%s: pattern %d not found",
+		e.Path,
+		e.PatternIndex,
+	)
+}
+
+func (e *PatternNotFoundError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+// CheckOffsets fails when the offsets found for a pattern do not match its count.
+func CheckOffsets(path string, patternIndex int, pattern *Pattern, offsets []int64) error {
+	if len(offsets) == 0 {
+		return &PatternNotFoundError{
+			Path:         path,
+			PatternIndex: patternIndex,
+		}
+	}
+
+	if len(offsets) != pattern.Count {
+		return &InvalidOffsetsLengthError{
+			Path:          path,
+			PatternIndex:  patternIndex,
+			PatternsCount: pattern.Count,
+			OffsetsLength: len(offsets),
+		}
+	}
+
+	return nil
+}
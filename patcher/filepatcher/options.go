@@ -0,0 +1,96 @@
+package filepatcher
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/libmodsig"
+)
+
+// Option configures a Patcher built by NewPatcher.
+type Option func(p *Patcher)
+
+// NewPatcher returns a patcher of the file at path. Without options it
+// patches the file on the OS filesystem, keeps the appended signature of a
+// kernel module, keeps backups next to the file and logs to the logger of
+// the context of every run.
+func NewPatcher(path string, opts ...Option) *Patcher {
+	p := &Patcher{
+		path:      path,
+		fs:        libfs.NewOSFS(),
+		now:       time.Now,
+		sigPolicy: patcher.SignaturePolicyKeep,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithResult makes Patch send its result to result.
+func WithResult(result chan<- patcher.Result) Option {
+	return func(p *Patcher) {
+		p.result = result
+	}
+}
+
+// WithLogger logs to logger, a nil logger selects the logger of the context
+// of every run.
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Patcher) {
+		p.logger = logger
+	}
+}
+
+// WithFS makes the patcher read, back up and replace the file through fsys
+// instead of the OS filesystem.
+func WithFS(fsys libfs.FS) Option {
+	return func(p *Patcher) {
+		p.fs = fsys
+	}
+}
+
+// WithClock uses now to timestamp backups.
+func WithClock(now func() time.Time) Option {
+	return func(p *Patcher) {
+		p.now = now
+	}
+}
+
+// WithDryRun makes the patcher search and validate every pattern in the file
+// opened read only, the result reports the bytes which would be patched.
+func WithDryRun(dryRun bool) Option {
+	return func(p *Patcher) {
+		p.dryRun = dryRun
+	}
+}
+
+// WithModuleSignature selects what happens to the appended signature of a
+// patched kernel module, signer is only used by the resign policy.
+func WithModuleSignature(policy patcher.SignaturePolicyEnum, signer *libmodsig.Signer) Option {
+	return func(p *Patcher) {
+		p.sigPolicy = policy
+		p.signer = signer
+	}
+}
+
+// WithBackupPolicy selects the location, naming and retention of backups
+// written when Patch is asked to keep one.
+func WithBackupPolicy(policy patcher.BackupPolicy) Option {
+	return func(p *Patcher) {
+		p.backupPol = policy
+	}
+}
+
+// WithProgress registers a callback invoked from the patching goroutine
+// each time a phase consumes more of its input.
+func WithProgress(progress patcher.ProgressFunc) Option {
+	return func(p *Patcher) {
+		p.progress = progress
+	}
+}
@@ -0,0 +1,244 @@
+package filepatcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/logging"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/libelf"
+	"github.com/grinderz/grgo/patcher/libmodsig"
+)
+
+const bufferSize = 8192
+
+var _ patcher.Patcher = (*Patcher)(nil)
+
+// Patcher patches a plain file such as an ELF binary or a firmware blob.
+// Patterns are applied to a working copy which atomically replaces the file,
+// so readers never see a partially patched file.
+type Patcher struct {
+	path      string
+	fs        libfs.FS
+	now       func() time.Time
+	dryRun    bool
+	sigPolicy patcher.SignaturePolicyEnum
+	signer    *libmodsig.Signer
+	backupPol patcher.BackupPolicy
+	result    chan<- patcher.Result
+	progress  patcher.ProgressFunc
+	logger    *zap.Logger
+}
+
+// New returns a patcher of the file at path, it is NewPatcher with the
+// WithResult and WithLogger options.
+func New(path string, result chan<- patcher.Result, logger *zap.Logger) *Patcher {
+	return NewPatcher(path, WithResult(result), WithLogger(logger))
+}
+
+// log returns the logger of a run: the given one or the one of ctx, with
+// the package and file path fields.
+func (p *Patcher) log(ctx context.Context) *zap.Logger {
+	logger := p.logger
+	if logger == nil {
+		logger = logging.FromContext(ctx)
+	}
+
+	return logger.With(logging.ZapFieldPkg("filepatcher"), logging.ZapFieldPath(p.path))
+}
+
+// Patch applies patterns and returns the result, which is also sent to the
+// result channel when one was given. On failure the result only holds the
+// path and the error.
+func (p *Patcher) Patch(patterns []*patcher.Pattern, backup bool) (patcher.Result, error) {
+	return p.PatchContext(context.Background(), patterns, backup)
+}
+
+// PatchContext is like Patch but stops as soon as ctx is done, the file of a
+// canceled run is left untouched.
+func (p *Patcher) PatchContext(
+	ctx context.Context,
+	patterns []*patcher.Pattern,
+	backup bool,
+) (patcher.Result, error) {
+	result, err := p.run(ctx, patterns, backup)
+
+	return patcher.Deliver(p.result, p.path, result, err)
+}
+
+func (p *Patcher) run(ctx context.Context, patterns []*patcher.Pattern, backup bool) (patcher.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return patcher.Result{}, err
+	}
+
+	if p.sigPolicy == patcher.SignaturePolicyResign && p.signer == nil {
+		return patcher.Result{}, patcher.ErrModuleSignerRequired
+	}
+
+	inFile, err := p.fs.OpenFile(p.path, os.O_RDONLY, 0)
+	if err != nil {
+		return patcher.Result{}, err //nolint:wrapcheck
+	}
+
+	defer inFile.Close()
+
+	size, err := libio.Size(inFile)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	run := &patcher.FileRun{
+		Path:       p.path,
+		BufferSize: bufferSize,
+		DryRun:     p.dryRun,
+		FilterELF:  libelf.FilterOffsets,
+		Progress:   p.progress,
+		Logger:     p.log(ctx),
+	}
+
+	if p.dryRun {
+		return p.search(ctx, run, inFile, patterns, size)
+	}
+
+	workFile, err := patcher.CreateWorkFile(p.fs, p.path, "")
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	defer func() {
+		if err := workFile.Remove(); err != nil {
+			p.log(ctx).Warn("remove work file failed", zap.String("file", workFile.Name()), zap.Error(err))
+		}
+	}()
+
+	input, err := patcher.HashReader(io.TeeReader(run.Reader(ctx, inFile, patcher.PhaseCopy, 0, size), workFile))
+	if err != nil {
+		return patcher.Result{}, fmt.Errorf("copy to work file failed: %w", err)
+	}
+
+	replaced, patternResults, err := run.Apply(ctx, workFile, patterns, size)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	result := patcher.NewResult(p.path, replaced)
+	result.Patterns = patternResults
+	result.Input = input
+	result.Phases = run.Phases()
+
+	if replaced == 0 {
+		return result, nil
+	}
+
+	if result.Signed, err = p.signature(ctx, workFile, size); err != nil {
+		return patcher.Result{}, err
+	}
+
+	if backup {
+		if result.Backup, err = run.Backup(p.fs, p.backupPol, inFile, p.now()); err != nil {
+			return patcher.Result{}, err
+		}
+	}
+
+	if _, err := workFile.Seek(0, io.SeekStart); err != nil {
+		return patcher.Result{}, fmt.Errorf("work file seek failed: %w", err)
+	}
+
+	output, err := patcher.HashReader(workFile)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	result.Output = &output
+
+	if err := workFile.Commit(); err != nil {
+		return patcher.Result{}, err
+	}
+
+	result.Phases = run.Phases()
+
+	return result, nil
+}
+
+// search validates patterns in the read only inFile and reports the bytes a
+// patch would replace without writing anything.
+func (p *Patcher) search(
+	ctx context.Context,
+	run *patcher.FileRun,
+	inFile libfs.File,
+	patterns []*patcher.Pattern,
+	size int64,
+) (patcher.Result, error) {
+	input, err := patcher.HashReader(inFile)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	replaced, patternResults, err := run.Apply(ctx, inFile, patterns, size)
+	if err != nil {
+		return patcher.Result{}, err
+	}
+
+	result := patcher.NewResult(p.path, replaced)
+	result.Patterns = patternResults
+	result.Input = input
+
+	if replaced > 0 {
+		if result.Signed, err = p.signature(ctx, inFile, size); err != nil {
+			return patcher.Result{}, err
+		}
+	}
+
+	result.Phases = run.Phases()
+
+	return result, nil
+}
+
+// signature applies the signature policy when the patched file is a kernel
+// module with an appended signature. A dry run only reports it.
+func (p *Patcher) signature(ctx context.Context, workFile libfs.File, size int64) ([]string, error) {
+	sig, ok, err := libmodsig.Find(workFile, size)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	policy := p.sigPolicy
+	if p.dryRun {
+		policy = patcher.SignaturePolicyKeep
+	}
+
+	switch policy {
+	case patcher.SignaturePolicyStrip:
+		p.log(ctx).Info("strip module signature")
+
+		if err := workFile.Truncate(sig.Offset); err != nil {
+			return nil, fmt.Errorf("work file truncate failed: %w", err)
+		}
+	case patcher.SignaturePolicyResign:
+		p.log(ctx).Info("resign module")
+
+		appendix, err := p.signer.Sign(io.NewSectionReader(workFile, 0, sig.Offset))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := workFile.Truncate(sig.Offset); err != nil {
+			return nil, fmt.Errorf("work file truncate failed: %w", err)
+		}
+
+		if _, err := workFile.WriteAt(appendix, sig.Offset); err != nil {
+			return nil, fmt.Errorf("work file write signature failed: %w", err)
+		}
+	case patcher.SignaturePolicyUnknown, patcher.SignaturePolicyKeep:
+		p.log(ctx).Warn("module signature invalidated")
+	}
+
+	return []string{p.path}, nil
+}
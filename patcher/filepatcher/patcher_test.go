@@ -0,0 +1,293 @@
+package filepatcher_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/logging"
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/filepatcher"
+	"github.com/grinderz/grgo/patcher/libmodsig"
+)
+
+func TestPatch(t *testing.T) {
+	t.Parallel()
+
+	original := []byte("\x7fELF..\x01\x02\x03..\x01\xFF\x03..")
+	path := filepath.Join(t.TempDir(), "blob.bin")
+
+	if err := os.WriteFile(path, original, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := []*patcher.Pattern{{
+		Description: "blob",
+		Count:       2,
+		Search:      []byte{0x01, 0x00, 0x03},
+		SearchMask:  []byte{0xFF, 0x00, 0xFF},
+		Replace:     []byte{0x09, 0x00, 0x09},
+		ReplaceMask: []byte{0xFF, 0x00, 0xFF},
+	}}
+
+	result := make(chan patcher.Result, 1)
+	opts := []filepatcher.Option{filepatcher.WithResult(result), filepatcher.WithLogger(zap.NewNop())}
+
+	filepatcher.NewPatcher(path, append(opts, filepatcher.WithDryRun(true))...).Patch(patterns, true)
+
+	if res := <-result; res.Err != nil || res.BytesPatched != 6 {
+		t.Fatalf("dry run result non valid: %+v", res)
+	}
+
+	checkFile(t, path, original)
+
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote backup: %v", err)
+	}
+
+	filepatcher.NewPatcher(path, opts...).Patch(patterns, true)
+
+	if res := <-result; res.Err != nil || res.BytesPatched != 6 {
+		t.Fatalf("result non valid: %+v", res)
+	}
+
+	checkFile(t, path, []byte("\x7fELF..\x09\x02\x09..\x09\xFF\x09.."))
+	checkFile(t, path+".bak", original)
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.Mode().Perm() != 0o750 {
+		t.Fatalf("mode non valid: %v", stat.Mode())
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("work files left: %v", entries)
+	}
+}
+
+func testPatterns() []*patcher.Pattern {
+	return []*patcher.Pattern{{Count: 1, Search: []byte("check_sig"), Replace: []byte("check_off")}}
+}
+
+func TestPatchSymlinkOwner(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blob.bin")
+	link := filepath.Join(dir, "link.bin")
+
+	if err := os.WriteFile(path, []byte("..check_sig.."), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("blob.bin", link); err != nil {
+		t.Fatal(err)
+	}
+
+	chowned := os.Geteuid() == 0
+	if chowned {
+		if err := os.Chown(path, 1234, 5678); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := filepatcher.New(link, nil, zap.NewNop()).Patch(testPatterns(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	linkStat, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if linkStat.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("symlink replaced: %v", linkStat.Mode())
+	}
+
+	checkFile(t, path, []byte("..check_off.."))
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.Mode().Perm() != 0o640 {
+		t.Fatalf("mode non valid: %v", stat.Mode())
+	}
+
+	if uid, gid, ok := libfs.Owner(stat); chowned && ok && (uid != 1234 || gid != 5678) {
+		t.Fatalf("owner non valid: %d:%d", uid, gid)
+	}
+}
+
+func TestPatchMemFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := libfs.NewMemFS()
+	fsys.WriteFile("blob.bin", []byte("..check_sig.."))
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	opts := []filepatcher.Option{
+		filepatcher.WithFS(fsys),
+		filepatcher.WithClock(func() time.Time { return now }),
+		filepatcher.WithBackupPolicy(patcher.BackupPolicy{Timestamp: true}),
+	}
+
+	p := filepatcher.NewPatcher("blob.bin", append(opts, filepatcher.WithDryRun(true))...)
+	if res, err := p.Patch(testPatterns(), true); err != nil || res.BytesPatched != 9 {
+		t.Fatalf("dry run result non valid: %+v %v", res, err)
+	}
+
+	if names := fsys.Names(); len(names) != 1 {
+		t.Fatalf("dry run wrote files: %v", names)
+	}
+
+	res, err := filepatcher.NewPatcher("blob.bin", opts...).Patch(testPatterns(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Backup != "blob.bin.20240506T070809Z.bak" {
+		t.Fatalf("backup non valid: %s", res.Backup)
+	}
+
+	names := fsys.Names()
+	slices.Sort(names)
+
+	if !slices.Equal(names, []string{"blob.bin", res.Backup}) {
+		t.Fatalf("files non valid: %v", names)
+	}
+
+	data, err := fsys.ReadFile("blob.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "..check_off.." {
+		t.Fatalf("content non valid: %q", data)
+	}
+}
+
+func TestPatchContextLogger(t *testing.T) {
+	t.Parallel()
+
+	fsys := libfs.NewMemFS()
+	fsys.WriteFile("blob.bin", []byte("..check_sig.."))
+
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := logging.ToContext(context.Background(), zap.New(core))
+
+	p := filepatcher.NewPatcher("blob.bin", filepatcher.WithFS(fsys))
+	if _, err := p.PatchContext(ctx, testPatterns(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	patched := logs.FilterMessage("patch").All()
+	if len(patched) != 1 {
+		t.Fatalf("patch log entries non valid: %+v", logs.All())
+	}
+
+	fields := patched[0].ContextMap()
+	if fields["path"] != "blob.bin" || fields["pkg"] != "filepatcher" || fields["phase"] != "patch" ||
+		fields["pattern"] != int64(0) || fields["offsets"] == nil {
+		t.Fatalf("patch log fields non valid: %+v", fields)
+	}
+}
+
+func checkFile(t *testing.T, path string, expected []byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != string(expected) {
+		t.Fatalf("%s content non valid: %q", path, data)
+	}
+}
+
+func TestPatchSignedModule(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "signing key"}}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer := &libmodsig.Signer{Key: key, Certificate: cert, Hash: crypto.SHA256}
+	content := []byte("\x7fELF..check_sig..")
+
+	appendix, err := signer.Sign(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "foo.ko")
+	if err := os.WriteFile(path, append(content, appendix...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := testPatterns()
+	result := make(chan patcher.Result, 1)
+	opts := []filepatcher.Option{filepatcher.WithResult(result), filepatcher.WithLogger(zap.NewNop())}
+
+	filepatcher.NewPatcher(path, append(opts, filepatcher.WithModuleSignature(patcher.SignaturePolicyResign, signer))...).
+		Patch(patterns, false)
+
+	if res := <-result; res.Err != nil || len(res.Signed) != 1 {
+		t.Fatalf("resign result non valid: %+v", res)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := libmodsig.Verify(bytes.NewReader(data), int64(len(data)), cert); err != nil {
+		t.Fatalf("resigned module non valid: %v", err)
+	}
+
+	filepatcher.NewPatcher(path, append(opts, filepatcher.WithModuleSignature(patcher.SignaturePolicyStrip, nil))...).
+		Patch(patcher.ReversePatterns(patterns), false)
+
+	if res := <-result; res.Err != nil || len(res.Signed) != 1 {
+		t.Fatalf("strip result non valid: %+v", res)
+	}
+
+	checkFile(t, path, content)
+}
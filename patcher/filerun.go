@@ -0,0 +1,157 @@
+package patcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/logging"
+)
+
+// ELFFilter keeps the offsets of an ELF anchored pattern which lie inside the
+// anchor, it is libelf.FilterOffsets.
+type ELFFilter func(reader io.ReaderAt, anchor *ELFAnchor, offsets []int64, length int) ([]int64, error)
+
+// FileRun holds the state of a single run patching one file, such as a plain
+// file or a decompressed payload: it searches and replaces the patterns,
+// reports progress and times the phases.
+type FileRun struct {
+	Path       string
+	BufferSize int
+	// DryRun only searches and validates the patterns, Apply counts the bytes
+	// it would replace without writing them.
+	DryRun    bool
+	FilterELF ELFFilter
+	Progress  ProgressFunc
+	// Logger carries the fields of the patcher running the file, nil
+	// discards the messages.
+	Logger *zap.Logger
+	timer  PhaseTimer
+}
+
+// Apply searches every pattern in file and replaces its matches in place.
+// Patterns are applied in order, so a pattern sees the replacements of the
+// previous ones. It returns the replaced bytes and the matches of every
+// pattern.
+func (r *FileRun) Apply(ctx context.Context, file libio.File, patterns []*Pattern, total int64) (int, []PatternResult, error) {
+	var (
+		replaced int
+		results  = make([]PatternResult, 0, len(patterns))
+	)
+
+	for patternIndex, pattern := range patterns {
+		r.log().Info(
+			"search",
+			logging.ZapFieldPhase(PhaseSearch),
+			logging.ZapFieldPattern(patternIndex),
+			logging.ZapFieldDescription(pattern.Description),
+		)
+
+		if len(pattern.Member) > 0 {
+			return 0, nil, fmt.Errorf("%s: pattern %d: %w", r.Path, patternIndex, ErrPatternMemberUnsupported)
+		}
+
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return 0, nil, fmt.Errorf("file seek failed: %w", err)
+		}
+
+		offsets, err := SearchBytesMask(
+			r.Reader(ctx, file, PhaseSearch, patternIndex, total),
+			pattern.Search,
+			pattern.SearchMask,
+			r.BufferSize,
+			pattern.Count,
+		)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if pattern.ELF != nil {
+			if offsets, err = r.FilterELF(file, pattern.ELF, offsets, len(pattern.Search)); err != nil {
+				return 0, nil, fmt.Errorf("%s: pattern %d: %w", r.Path, patternIndex, err)
+			}
+		}
+
+		if err := CheckOffsets(r.Path, patternIndex, pattern, offsets); err != nil {
+			return 0, nil, err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err //nolint:wrapcheck
+		}
+
+		results = append(results, PatternResult{
+			Index:       patternIndex,
+			Description: pattern.Description,
+			Offsets:     offsets,
+		})
+
+		if r.DryRun {
+			replaced += len(offsets) * len(pattern.Replace)
+			continue
+		}
+
+		r.log().Info(
+			"patch",
+			logging.ZapFieldPhase(PhasePatch),
+			logging.ZapFieldPattern(patternIndex),
+			logging.ZapFieldDescription(pattern.Description),
+			logging.ZapFieldOffsets(offsets),
+		)
+		r.Report(PhasePatch, patternIndex, 0, int64(len(offsets)))
+
+		rbs, err := ReplaceBytesMask(file, offsets, pattern.Replace, pattern.ReplaceMask)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		replaced += rbs
+	}
+
+	return replaced, results, nil
+}
+
+// Backup writes the content of reader from its start as the backup of the
+// file following policy.
+func (r *FileRun) Backup(fsys libfs.FS, policy BackupPolicy, reader io.ReadSeeker, now time.Time) (string, error) {
+	r.log().Info("backup", logging.ZapFieldPhase(PhaseBackup))
+	r.Report(PhaseBackup, 0, 0, 0)
+
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("file seek failed: %w", err)
+	}
+
+	return policy.Write(fsys, r.Path, reader, now)
+}
+
+func (r *FileRun) log() *zap.Logger {
+	if r.Logger == nil {
+		return zap.NewNop()
+	}
+
+	return r.Logger
+}
+
+// Report starts timing phase and reports its progress.
+func (r *FileRun) Report(phase PhaseEnum, patternIndex int, processed, total int64) {
+	r.timer.Start(phase)
+	r.Progress.Report(r.Path, phase, patternIndex, processed, total)
+}
+
+// Reader starts timing phase and wraps reader to report its progress and
+// stop once ctx is done.
+func (r *FileRun) Reader(ctx context.Context, reader io.Reader, phase PhaseEnum, patternIndex int, total int64) io.Reader {
+	r.timer.Start(phase)
+
+	return r.Progress.Reader(ctx, reader, r.Path, phase, patternIndex, total)
+}
+
+// Phases returns the time spent in every phase so far.
+func (r *FileRun) Phases() []PhaseTime {
+	return r.timer.Phases()
+}
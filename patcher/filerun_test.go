@@ -0,0 +1,107 @@
+package patcher_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/grinderz/grgo/libio"
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestFileRunApply(t *testing.T) {
+	t.Parallel()
+
+	patterns := []*patcher.Pattern{
+		{Description: "first", Count: 2, Search: []byte("ab"), Replace: []byte("cd")},
+		{Description: "second", Count: 1, Search: []byte("cdx"), Replace: []byte("yyy")},
+	}
+
+	tests := []struct {
+		name     string
+		dryRun   bool
+		patterns []*patcher.Pattern
+		data     string
+		replaced int
+		err      error
+	}{
+		{name: "apply", patterns: patterns, data: "..cd..yyy..", replaced: 7},
+		{name: "dry run", dryRun: true, patterns: patterns[:1], data: "..ab..abx..", replaced: 4},
+		{name: "member", patterns: []*patcher.Pattern{{Count: 1, Search: []byte("ab"), Replace: []byte("cd"), Member: "a"}}, data: "..ab..abx..", err: patcher.ErrPatternMemberUnsupported},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			file := libio.NewBuffer([]byte("..ab..abx.."))
+			phases := make([]patcher.PhaseEnum, 0)
+			run := &patcher.FileRun{
+				Path:       "blob",
+				BufferSize: 4,
+				DryRun:     test.dryRun,
+				Logger:     zap.NewNop(),
+				Progress: func(progress patcher.Progress) {
+					phases = append(phases, progress.Phase)
+				},
+			}
+
+			replaced, results, err := run.Apply(context.Background(), file, test.patterns, int64(file.Len()))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+
+			if string(file.Bytes()) != test.data {
+				t.Fatalf("data non valid: %q", file.Bytes())
+			}
+
+			if test.err != nil {
+				return
+			}
+
+			if replaced != test.replaced || len(results) != len(test.patterns) {
+				t.Fatalf("result non valid: %d %+v", replaced, results)
+			}
+
+			if slices.Contains(phases, patcher.PhasePatch) == test.dryRun {
+				t.Fatalf("phases non valid: %v", phases)
+			}
+		})
+	}
+}
+
+func TestFileRunBackup(t *testing.T) {
+	t.Parallel()
+
+	fsys := newWorkFS(t)
+	run := &patcher.FileRun{Path: "dir/blob", Logger: zap.NewNop()}
+
+	inFile, err := fsys.OpenFile("dir/blob", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.Copy(io.Discard, inFile); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := run.Backup(fsys, patcher.BackupPolicy{}, inFile, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fsys.ReadFile(path)
+	if err != nil || string(data) != "original" {
+		t.Fatalf("backup non valid: %q %v", data, err)
+	}
+
+	if phases := run.Phases(); len(phases) != 1 || phases[0].Phase != patcher.PhaseBackup {
+		t.Fatalf("phases non valid: %+v", phases)
+	}
+}
@@ -1,38 +1,70 @@
 package patcher
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"os"
 )
 
+// Pattern describes a single search and replace.
+// A zero byte in SearchMask marks a wildcard position in Search, a zero byte in
+// ReplaceMask keeps the original byte at that position. Nil masks match and
+// replace every byte. Member restricts matches to a single archive member
+// when the patcher understands the container format, ELF anchors the pattern
+// inside an ELF file or member.
 type Pattern struct {
 	Description string
 	Count       int
 	Search      []byte
+	SearchMask  []byte
 	Replace     []byte
+	ReplaceMask []byte
+	Member      string
+	ELF         *ELFAnchor
+	Versions    VersionConstraint
 }
 
-type Result struct {
-	Path         string
-	BytesPatched int
-	Err          error
+// ELFAnchor restricts matches to the file data of an ELF section, or pins
+// the match to the address of a symbol plus Offset.
+type ELFAnchor struct {
+	Section string
+	Symbol  string
+	Offset  int64
 }
 
-func NewResult(path string, bytesPatched int) Result {
-	return Result{path, bytesPatched, nil}
+func ReplaceBytes(file ReadWriterAt, offsets []int64, replace []byte) (int, error) {
+	return ReplaceBytesMask(file, offsets, replace, nil)
 }
 
-func NewError(path string, err error) Result {
-	return Result{path, 0, err}
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
 }
 
-func ReplaceBytes(file *os.File, offsets []int64, replace []byte) (int, error) {
+type syncer interface {
+	Sync() error
+}
+
+// ReplaceBytesMask writes replace at every offset, keeping the original bytes
+// where mask is zero. The file is synced when it supports it.
+func ReplaceBytesMask(file ReadWriterAt, offsets []int64, replace, mask []byte) (int, error) {
 	var totalReplaced int
 
+	buff := replace
+	if mask != nil {
+		buff = make([]byte, len(replace))
+	}
+
 	for _, offset := range offsets {
-		replaced, err := file.WriteAt(replace, offset)
+		if mask != nil {
+			if _, err := file.ReadAt(buff, offset); err != nil {
+				return 0, fmt.Errorf("read original bytes failed: %w", err)
+			}
+
+			applyMask(buff, replace, mask)
+		}
+
+		replaced, err := file.WriteAt(buff, offset)
 		if err != nil {
 			return 0, fmt.Errorf("patching file failed: %w", err)
 		}
@@ -40,46 +72,62 @@ func ReplaceBytes(file *os.File, offsets []int64, replace []byte) (int, error) {
 		totalReplaced += replaced
 	}
 
-	if err := file.Sync(); err != nil {
-		return 0, fmt.Errorf("patched file sync failed: %w", err)
+	if sync, ok := file.(syncer); ok {
+		if err := sync.Sync(); err != nil {
+			return 0, fmt.Errorf("patched file sync failed: %w", err)
+		}
 	}
 
 	return totalReplaced, nil
 }
 
 func SearchBytes(f io.Reader, find []byte, buffSize int, resultCap int) ([]int64, error) {
+	return SearchBytesMask(f, find, nil, buffSize, resultCap)
+}
+
+// SearchBytesMask returns the offsets of all non-overlapping occurrences of
+// find in f, treating positions with a zero mask byte as wildcards.
+func SearchBytesMask(f io.Reader, find, mask []byte, buffSize int, resultCap int) ([]int64, error) {
 	result := make([]int64, 0, resultCap)
+	findLen := len(find)
+
+	if findLen == 0 {
+		return result, nil
+	}
 
 	buff := make([]byte, buffSize)
-	reader := bufio.NewReader(f)
-	findLen := len(find)
+	window := make([]byte, 0, buffSize+findLen)
 
 	var (
-		totalRead   int64
-		matchIndex  int
-		readCounter int
-		err         error
+		windowOffset int64
+		nextOffset   int64
+		readCounter  int
+		err          error
 	)
 
 	for {
-		if readCounter, err = reader.Read(buff); err != nil && err != io.EOF {
+		if readCounter, err = f.Read(buff); err != nil && err != io.EOF {
 			return nil, fmt.Errorf("read buffer failed: %w", err)
 		}
 
-		for ind, b := range buff {
-			if b != find[matchIndex] {
-				matchIndex = 0
-				continue
-			}
+		window = append(window, buff[:readCounter]...)
 
-			matchIndex++
-			if matchIndex == findLen {
-				result = append(result, totalRead-int64(matchIndex)+int64(ind)+1)
-				matchIndex = 0
+		for ind := int(max(nextOffset-windowOffset, 0)); ind+findLen <= len(window); {
+			next := indexMask(window[ind:], find, mask)
+			if next < 0 {
+				break
 			}
+
+			ind += next
+			result = append(result, windowOffset+int64(ind))
+			ind += findLen
+			nextOffset = windowOffset + int64(ind)
 		}
 
-		totalRead += int64(readCounter)
+		if keep := findLen - 1; len(window) > keep {
+			windowOffset += int64(len(window) - keep)
+			window = append(window[:0], window[len(window)-keep:]...)
+		}
 
 		if err == io.EOF {
 			break
@@ -88,3 +136,98 @@ func SearchBytes(f io.Reader, find []byte, buffSize int, resultCap int) ([]int64
 
 	return result, nil
 }
+
+func indexMask(data, find, mask []byte) int {
+	if mask == nil {
+		return bytes.Index(data, find)
+	}
+
+	for ind := 0; ind+len(find) <= len(data); ind++ {
+		if equalMask(data[ind:ind+len(find)], find, mask) {
+			return ind
+		}
+	}
+
+	return -1
+}
+
+func equalMask(data, find, mask []byte) bool {
+	for ind, b := range find {
+		if mask[ind] != 0 && data[ind]&mask[ind] != b&mask[ind] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func applyMask(dst, src, mask []byte) {
+	for ind, b := range src {
+		dst[ind] = dst[ind]&^mask[ind] | b&mask[ind]
+	}
+}
+
+// CheckLength fails with ErrPatternSetLength unless the replacement and the
+// masks have the length of the searched bytes.
+func (p *Pattern) CheckLength() error {
+	if len(p.Replace) != len(p.Search) {
+		return fmt.Errorf("%w: search %d bytes, replace %d bytes", ErrPatternSetLength, len(p.Search), len(p.Replace))
+	}
+
+	if p.SearchMask != nil && len(p.SearchMask) != len(p.Search) {
+		return fmt.Errorf("%w: search %d bytes, mask %d bytes", ErrPatternSetLength, len(p.Search), len(p.SearchMask))
+	}
+
+	if p.ReplaceMask != nil && len(p.ReplaceMask) != len(p.Replace) {
+		return fmt.Errorf("%w: replace %d bytes, mask %d bytes", ErrPatternSetLength, len(p.Replace), len(p.ReplaceMask))
+	}
+
+	return nil
+}
+
+// Reverse returns a pattern which undoes p: it searches for the replacement
+// and writes the original bytes back.
+func (p *Pattern) Reverse() *Pattern {
+	return &Pattern{
+		Description: p.Description,
+		Count:       p.Count,
+		Search:      p.Replace,
+		SearchMask:  p.ReplaceMask,
+		Replace:     p.Search,
+		ReplaceMask: p.SearchMask,
+		Member:      p.Member,
+		ELF:         p.ELF,
+		Versions:    p.Versions,
+	}
+}
+
+func ReversePatterns(patterns []*Pattern) []*Pattern {
+	reversed := make([]*Pattern, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		reversed = append(reversed, pattern.Reverse())
+	}
+
+	return reversed
+}
+
+type PatternState struct {
+	Index        int
+	Description  string
+	State        PatternStateEnum
+	SearchCount  int
+	ReplaceCount int
+}
+
+func NewPatternState(index int, pattern *Pattern, searchCount, replaceCount int) PatternState {
+	state := PatternStateMismatch
+
+	switch {
+	case searchCount == pattern.Count:
+		state = PatternStateUnpatched
+	case replaceCount == pattern.Count:
+		state = PatternStatePatched
+	}
+
+	return PatternState{index, pattern.Description, state, searchCount, replaceCount}
+}
@@ -0,0 +1,46 @@
+package patcher_test
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestSearchBytesMask(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("xaaab--aXb--aab")
+
+	offsets, err := patcher.SearchBytes(bytes.NewReader(data), []byte("aab"), 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(offsets, []int64{2, 12}) {
+		t.Fatalf("offsets non valid: %v", offsets)
+	}
+
+	offsets, err = patcher.SearchBytesMask(bytes.NewReader(data), []byte("a?b"), []byte{0xFF, 0x00, 0xFF}, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(offsets, []int64{2, 7, 12}) {
+		t.Fatalf("masked offsets non valid: %v", offsets)
+	}
+}
+
+func TestSearchBytesShortRead(t *testing.T) {
+	t.Parallel()
+
+	offsets, err := patcher.SearchBytes(bytes.NewReader([]byte("xxabc")), []byte("ab"), 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(offsets, []int64{2}) {
+		t.Fatalf("offsets non valid: %v", offsets)
+	}
+}
@@ -0,0 +1,196 @@
+package libelf
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/patcher"
+)
+
+// Range is a half open range of file offsets.
+type Range struct {
+	Start int64
+	End   int64
+}
+
+func (r Range) Contains(offset, length int64) bool {
+	return offset >= r.Start && offset+length <= r.End
+}
+
+func SectionRange(file *elf.File, name string) (Range, error) {
+	section := file.Section(name)
+	if section == nil {
+		return Range{}, &SectionNotFoundError{Name: name}
+	}
+
+	return sectionRange(section)
+}
+
+func sectionRange(section *elf.Section) (Range, error) {
+	if section.Type == elf.SHT_NOBITS {
+		return Range{}, &SectionNoDataError{Name: section.Name}
+	}
+
+	return Range{
+		Start: int64(section.Offset),
+		End:   int64(section.Offset + section.FileSize),
+	}, nil
+}
+
+// SymbolOffset converts the address of the named symbol plus offset into a
+// file offset, returning it with the name and range of the symbol section.
+// Symbol values of relocatable objects like kernel modules are section
+// relative, which the conversion handles since their sections have no address.
+func SymbolOffset(file *elf.File, name string, offset int64) (int64, string, Range, error) {
+	symbol, err := findSymbol(file, name)
+	if err != nil {
+		return 0, "", Range{}, err
+	}
+
+	if symbol.Section == elf.SHN_UNDEF || int(symbol.Section) >= len(file.Sections) {
+		return 0, "", Range{}, &SymbolNotMappedError{Name: name}
+	}
+
+	section := file.Sections[symbol.Section]
+
+	sectionRange, err := sectionRange(section)
+	if err != nil {
+		return 0, "", Range{}, err
+	}
+
+	fileOffset := int64(section.Offset) + int64(symbol.Value-section.Addr) + offset
+
+	return fileOffset, section.Name, sectionRange, nil
+}
+
+func findSymbol(file *elf.File, name string) (elf.Symbol, error) {
+	symbols, err := file.Symbols()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+		return elf.Symbol{}, fmt.Errorf("read symbols failed: %w", liberr.Corrupt(err))
+	}
+
+	dynamic, err := file.DynamicSymbols()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+		return elf.Symbol{}, fmt.Errorf("read dynamic symbols failed: %w", liberr.Corrupt(err))
+	}
+
+	for _, symbol := range append(symbols, dynamic...) {
+		if symbol.Name == name {
+			return symbol, nil
+		}
+	}
+
+	return elf.Symbol{}, &SymbolNotFoundError{Name: name}
+}
+
+// FilterOffsets keeps the offsets of an ELF image read from reader which
+// satisfy the anchor: matches must lie inside the anchor section, a symbol
+// anchor keeps only the match at the symbol address plus offset. A symbol
+// resolving outside the anchor section is an error.
+func FilterOffsets(reader io.ReaderAt, anchor *patcher.ELFAnchor, offsets []int64, length int) ([]int64, error) {
+	file, err := elf.NewFile(reader)
+	if err != nil {
+		return nil, fmt.Errorf("elf parse failed: %w", liberr.Corrupt(err))
+	}
+
+	defer file.Close()
+
+	allowed := Range{Start: 0, End: 1<<63 - 1}
+
+	if len(anchor.Section) > 0 {
+		if allowed, err = SectionRange(file, anchor.Section); err != nil {
+			return nil, err
+		}
+	}
+
+	target := int64(-1)
+
+	if len(anchor.Symbol) > 0 {
+		symbolOffset, section, symbolRange, err := SymbolOffset(file, anchor.Symbol, anchor.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(anchor.Section) > 0 && section != anchor.Section {
+			return nil, &SymbolOutsideSectionError{Symbol: anchor.Symbol, Section: anchor.Section}
+		}
+
+		target, allowed = symbolOffset, symbolRange
+	}
+
+	filtered := make([]int64, 0, len(offsets))
+
+	for _, offset := range offsets {
+		if !allowed.Contains(offset, int64(length)) || (target >= 0 && offset != target) {
+			continue
+		}
+
+		filtered = append(filtered, offset)
+	}
+
+	return filtered, nil
+}
+
+type SectionNotFoundError struct {
+	Name string
+}
+
+func (e *SectionNotFoundError) Error() string {
+	return fmt.Sprintf("elf section %s not found", e.Name)
+}
+
+func (e *SectionNotFoundError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type SectionNoDataError struct {
+	Name string
+}
+
+func (e *SectionNoDataError) Error() string {
+	return fmt.Sprintf("elf section %s has no file data", e.Name)
+}
+
+func (e *SectionNoDataError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type SymbolNotFoundError struct {
+	Name string
+}
+
+func (e *SymbolNotFoundError) Error() string {
+	return fmt.Sprintf("elf symbol %s not found", e.Name)
+}
+
+func (e *SymbolNotFoundError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type SymbolNotMappedError struct {
+	Name string
+}
+
+func (e *SymbolNotMappedError) Error() string {
+	return fmt.Sprintf("elf symbol %s is not defined in a section", e.Name)
+}
+
+func (e *SymbolNotMappedError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type SymbolOutsideSectionError struct {
+	Symbol  string
+	Section string
+}
+
+func (e *SymbolOutsideSectionError) Error() string {
+	return fmt.Sprintf("elf symbol %s is outside section %s", e.Symbol, e.Section)
+}
+
+func (e *SymbolOutsideSectionError) Unwrap() error {
+	return liberr.ErrInvalid
+}
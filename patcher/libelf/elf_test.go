@@ -0,0 +1,89 @@
+package libelf_test
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+	"github.com/grinderz/grgo/patcher/libelf"
+)
+
+// testdata/module.o is a relocatable x86-64 object: .text at 0x40 holds
+// check_sig, .rodata at 0x50 holds "PATCHME-RODATA" twice, other at 0x00
+// and banner at 0x10.
+const (
+	textOffset   = 0x40
+	rodataOffset = 0x50
+)
+
+func testOffsets(t *testing.T, anchor *patcher.ELFAnchor, search []byte) ([]int64, error) {
+	t.Helper()
+
+	file, err := os.Open("testdata/module.o")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	offsets, err := patcher.SearchBytes(file, search, 64, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return libelf.FilterOffsets(file, anchor, offsets, len(search))
+}
+
+func TestFilterOffsets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		anchor patcher.ELFAnchor
+		search string
+		want   []int64
+	}{
+		{"section", patcher.ELFAnchor{Section: ".rodata"}, "PATCHME-RODATA", []int64{rodataOffset, rodataOffset + 0x10}},
+		{"other section", patcher.ELFAnchor{Section: ".text"}, "PATCHME-RODATA", []int64{}},
+		{"symbol", patcher.ELFAnchor{Symbol: "banner"}, "PATCHME-RODATA", []int64{rodataOffset + 0x10}},
+		{"symbol offset", patcher.ELFAnchor{Section: ".text", Symbol: "check_sig", Offset: 2}, "\x81\xff", []int64{textOffset + 2}},
+		{"symbol wrong offset", patcher.ELFAnchor{Symbol: "check_sig", Offset: 3}, "\x81\xff", []int64{}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			offsets, err := testOffsets(t, &test.anchor, []byte(test.search))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(offsets, test.want) {
+				t.Fatalf("offsets non valid: %v, expected %v", offsets, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterOffsetsErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := testOffsets(t, &patcher.ELFAnchor{Section: ".data.missing"}, []byte("PATCHME"))
+
+	var sectionErr *libelf.SectionNotFoundError
+	if !errors.As(err, &sectionErr) {
+		t.Fatalf("missing section error non valid: %v", err)
+	}
+
+	_, err = testOffsets(t, &patcher.ELFAnchor{Section: ".text", Symbol: "banner"}, []byte("PATCHME"))
+
+	var outsideErr *libelf.SymbolOutsideSectionError
+	if !errors.As(err, &outsideErr) {
+		t.Fatalf("symbol outside section error non valid: %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package libmodsig
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/grinderz/grgo/liberr"
+	"github.com/grinderz/grgo/librsa"
+)
+
+// Magic terminates a kernel module carrying an appended signature.
+const Magic = "~Module signature appended~\n"
+
+// infoSize is the size of struct module_signature preceding Magic.
+const (
+	infoSize    = 12
+	pkeyIDPKCS7 = 2
+)
+
+//nolint:gochecknoglobals
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+
+	hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA1:   {1, 3, 14, 3, 2, 26},
+		crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+		crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+		crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+	}
+)
+
+// Signature locates an appended module signature: the module content ends
+// at Offset, the PKCS#7 message, signature info and Magic fill Size bytes.
+type Signature struct {
+	Offset int64
+	Size   int64
+	IDType uint8
+	SigLen uint32
+}
+
+// Find looks for an appended signature at the end of the size bytes read from reader.
+func Find(reader io.ReaderAt, size int64) (Signature, bool, error) {
+	tailSize := int64(infoSize + len(Magic))
+	if size < tailSize {
+		return Signature{}, false, nil
+	}
+
+	tail := make([]byte, tailSize)
+	if _, err := reader.ReadAt(tail, size-tailSize); err != nil {
+		return Signature{}, false, fmt.Errorf("read signature tail failed: %w", err)
+	}
+
+	if string(tail[infoSize:]) != Magic {
+		return Signature{}, false, nil
+	}
+
+	sig := Signature{
+		IDType: tail[2],
+		SigLen: binary.BigEndian.Uint32(tail[8:infoSize]),
+	}
+
+	sig.Size = tailSize + int64(sig.SigLen)
+	sig.Offset = size - sig.Size
+
+	if sig.Offset < 0 {
+		return Signature{}, false, &CorruptError{Reason: "signature length exceeds module size"}
+	}
+
+	return sig, true, nil
+}
+
+// Signer produces module signatures the way the kernel sign-file tool does:
+// a detached PKCS#7 message without authenticated attributes and certificates.
+type Signer struct {
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+	Hash        crypto.Hash
+}
+
+// LoadSigner reads the RSA key and X.509 certificate from PEM files. An
+// empty certPath reads both from keyPath like the kernel signing_key.pem.
+func LoadSigner(keyPath, certPath string, hash crypto.Hash) (*Signer, error) {
+	if len(certPath) == 0 {
+		certPath = keyPath
+	}
+
+	if _, ok := hashOIDs[hash]; !ok {
+		return nil, &UnsupportedHashError{Hash: hash}
+	}
+
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key failed: %w", err)
+	}
+
+	key, err := librsa.ParsePrivateKeyPEM(keyData)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("read signing certificate failed: %w", err)
+	}
+
+	cert, err := parseCertificatePEM(certData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{Key: key, Certificate: cert, Hash: hash}, nil
+}
+
+func parseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse certificate failed: %w", liberr.Wrap(liberr.ErrInvalid, err))
+			}
+
+			return cert, nil
+		}
+	}
+
+	return nil, ErrCertificateNotFound
+}
+
+// Sign returns the signature to append to the module content read from reader.
+func (s *Signer) Sign(reader io.Reader) ([]byte, error) {
+	hashOID, ok := hashOIDs[s.Hash]
+	if !ok {
+		return nil, &UnsupportedHashError{Hash: s.Hash}
+	}
+
+	digest, err := hashReader(s.Hash, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := rsa.SignPKCS1v15(rand.Reader, s.Key, s.Hash, digest)
+	if err != nil {
+		return nil, fmt.Errorf("rsa sign failed: %w", err)
+	}
+
+	digestAlgorithm := pkix.AlgorithmIdentifier{Algorithm: hashOID}
+
+	signed, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		ContentInfo:      dataInfo{ContentType: oidData},
+		SignerInfos: []signerInfo{{
+			Version: 1,
+			IssuerAndSerialNumber: issuerAndSerial{
+				Issuer:       asn1.RawValue{FullBytes: s.Certificate.RawIssuer},
+				SerialNumber: s.Certificate.SerialNumber,
+			},
+			DigestAlgorithm: digestAlgorithm,
+			DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidRSAEncryption,
+				Parameters: asn1.NullRawValue,
+			},
+			EncryptedDigest: encrypted,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pkcs7 marshal failed: %w", err)
+	}
+
+	message, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pkcs7 marshal failed: %w", err)
+	}
+
+	info := make([]byte, infoSize)
+	info[2] = pkeyIDPKCS7
+	binary.BigEndian.PutUint32(info[8:], uint32(len(message)))
+
+	return append(append(message, info...), Magic...), nil
+}
+
+// Verify checks the appended signature of the module read from reader
+// against cert. Only messages produced like Sign does are understood.
+func Verify(reader io.ReaderAt, size int64, cert *x509.Certificate) error {
+	sig, ok, err := Find(reader, size)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrSignatureNotFound
+	}
+
+	if sig.IDType != pkeyIDPKCS7 {
+		return &CorruptError{Reason: fmt.Sprintf("unsupported signature id type %d", sig.IDType)}
+	}
+
+	message := make([]byte, sig.SigLen)
+	if _, err := reader.ReadAt(message, sig.Offset); err != nil {
+		return fmt.Errorf("read signature failed: %w", err)
+	}
+
+	info, err := parseMessage(message)
+	if err != nil {
+		return err
+	}
+
+	if info.IssuerAndSerialNumber.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		return ErrSignerMismatch
+	}
+
+	hash, err := hashFromOID(info.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	digest, err := hashReader(hash, io.NewSectionReader(reader, 0, sig.Offset))
+	if err != nil {
+		return err
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return ErrSignerMismatch
+	}
+
+	if err := rsa.VerifyPKCS1v15(pub, hash, digest, info.EncryptedDigest); err != nil {
+		return fmt.Errorf("module signature verify failed: %w", liberr.Wrap(liberr.ErrCorrupt, err))
+	}
+
+	return nil
+}
+
+func parseMessage(message []byte) (signerInfo, error) {
+	var outer contentInfo
+	if _, err := asn1.Unmarshal(message, &outer); err != nil || !outer.ContentType.Equal(oidSignedData) {
+		return signerInfo{}, &CorruptError{Reason: "not a pkcs7 signed data message"}
+	}
+
+	var signed signedData
+	if _, err := asn1.Unmarshal(outer.Content.Bytes, &signed); err != nil || len(signed.SignerInfos) != 1 {
+		return signerInfo{}, &CorruptError{Reason: "pkcs7 signer info not found"}
+	}
+
+	return signed.SignerInfos[0], nil
+}
+
+func hashFromOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	for hash, hashOID := range hashOIDs {
+		if hashOID.Equal(oid) {
+			return hash, nil
+		}
+	}
+
+	return 0, &CorruptError{Reason: fmt.Sprintf("unsupported digest algorithm %s", oid)}
+}
+
+func hashReader(hash crypto.Hash, reader io.Reader) ([]byte, error) {
+	hasher := hash.New()
+	if _, err := io.Copy(hasher, reader); err != nil {
+		return nil, fmt.Errorf("hash module failed: %w", err)
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type dataInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      dataInfo
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+var (
+	ErrSignatureNotFound   = liberr.New(liberr.ErrInvalid, "module signature not found")
+	ErrSignerMismatch      = liberr.New(liberr.ErrInvalid, "module signature signer mismatch")
+	ErrCertificateNotFound = liberr.New(liberr.ErrInvalid, "pem certificate not found")
+)
+
+type CorruptError struct {
+	Reason string
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("module signature corrupt: %s", e.Reason)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return liberr.ErrCorrupt
+}
+
+type UnsupportedHashError struct {
+	Hash crypto.Hash
+}
+
+func (e *UnsupportedHashError) Error() string {
+	return fmt.Sprintf("module signature hash %s not supported", e.Hash)
+}
+
+func (e *UnsupportedHashError) Unwrap() error {
+	return liberr.ErrInvalid
+}
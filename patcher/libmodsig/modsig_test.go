@@ -0,0 +1,86 @@
+package libmodsig_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/grinderz/grgo/patcher/libmodsig"
+)
+
+func newSigner(t *testing.T) *libmodsig.Signer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "module signing key"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &libmodsig.Signer{Key: key, Certificate: cert, Hash: crypto.SHA256}
+}
+
+func TestSignVerify(t *testing.T) {
+	t.Parallel()
+
+	signer := newSigner(t)
+	content := []byte("\x7fELF module content")
+
+	appendix, err := signer.Sign(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module := append(append([]byte{}, content...), appendix...)
+
+	sig, ok, err := libmodsig.Find(bytes.NewReader(module), int64(len(module)))
+	if err != nil || !ok {
+		t.Fatalf("find non valid: %v %v", ok, err)
+	}
+
+	if sig.Offset != int64(len(content)) || sig.Size != int64(len(appendix)) {
+		t.Fatalf("signature non valid: %+v", sig)
+	}
+
+	if err := libmodsig.Verify(bytes.NewReader(module), int64(len(module)), signer.Certificate); err != nil {
+		t.Fatal(err)
+	}
+
+	module[1] = 'e'
+
+	if err := libmodsig.Verify(bytes.NewReader(module), int64(len(module)), signer.Certificate); err == nil {
+		t.Fatal("tampered module verified")
+	}
+
+	if _, ok, _ := libmodsig.Find(bytes.NewReader(content), int64(len(content))); ok {
+		t.Fatal("unsigned module has signature")
+	}
+
+	err = libmodsig.Verify(bytes.NewReader(content), int64(len(content)), signer.Certificate)
+	if !errors.Is(err, libmodsig.ErrSignatureNotFound) {
+		t.Fatalf("unsigned verify error non valid: %v", err)
+	}
+}
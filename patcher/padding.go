@@ -0,0 +1,40 @@
+package patcher
+
+// MaxPaddingAlign bounds the alignment recorded for padded segments.
+const MaxPaddingAlign = 4096
+
+// PaddingAlign returns the alignment of a padded segment ending at end: the
+// largest power of two up to MaxPaddingAlign dividing end.
+func PaddingAlign(end int64) int64 {
+	align := int64(1)
+	for align < MaxPaddingAlign && end%(align*2) == 0 {
+		align *= 2
+	}
+
+	return align
+}
+
+// RestorePadding returns the number of zero bytes to write after the
+// repacked segment ending at end. The align policy, also used for
+// PaddingPolicyUnknown, pads end to the recorded alignment, keep writes the
+// recorded padding again and drop writes none. Segments recorded without
+// padding get none.
+func (s Segment) RestorePadding(end int64, policy PaddingPolicyEnum) int64 {
+	if s.Padding <= 0 {
+		return 0
+	}
+
+	switch policy {
+	case PaddingPolicyKeep:
+		return s.Padding
+	case PaddingPolicyDrop:
+		return 0
+	case PaddingPolicyAlign, PaddingPolicyUnknown:
+	}
+
+	if s.Align <= 1 {
+		return 0
+	}
+
+	return (s.Align - end%s.Align) % s.Align
+}
@@ -0,0 +1,70 @@
+package patcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=PaddingPolicyEnum -linecomment -output padding_policy_enum_string.go
+type PaddingPolicyEnum int
+
+const (
+	PaddingPolicyUnknown PaddingPolicyEnum = iota // unknown
+	PaddingPolicyAlign   PaddingPolicyEnum = iota // align
+	PaddingPolicyKeep    PaddingPolicyEnum = iota // keep
+	PaddingPolicyDrop    PaddingPolicyEnum = iota // drop
+)
+
+func (e *PaddingPolicyEnum) SetValue(value string) error {
+	mode := PaddingPolicyFromString(value)
+	if mode == PaddingPolicyUnknown {
+		return &PaddingPolicyValueError{
+			Value: value,
+		}
+	}
+
+	*e = mode
+
+	return nil
+}
+
+func (e PaddingPolicyEnum) MarshalText() ([]byte, error) {
+	if e == PaddingPolicyUnknown {
+		return nil, &PaddingPolicyValueError{
+			Value: PaddingPolicyUnknown.String(),
+		}
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *PaddingPolicyEnum) UnmarshalText(text []byte) error {
+	return e.SetValue(string(text))
+}
+
+func PaddingPolicyFromString(value string) PaddingPolicyEnum {
+	switch strings.ToLower(value) {
+	case "align":
+		return PaddingPolicyAlign
+	case "keep":
+		return PaddingPolicyKeep
+	case "drop":
+		return PaddingPolicyDrop
+	default:
+		return PaddingPolicyUnknown
+	}
+}
+
+type PaddingPolicyValueError struct {
+	Value string
+}
+
+func (e *PaddingPolicyValueError) Error() string {
+	return fmt.Sprintf("padding policy invalid value: %s", e.Value)
+}
+
+func (e *PaddingPolicyValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=PaddingPolicyEnum -linecomment -output padding_policy_enum_string.go"; DO NOT EDIT.
+
+package patcher
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[PaddingPolicyUnknown-0]
+	_ = x[PaddingPolicyAlign-1]
+	_ = x[PaddingPolicyKeep-2]
+	_ = x[PaddingPolicyDrop-3]
+}
+
+const _PaddingPolicyEnum_name = "unknownalignkeepdrop"
+
+var _PaddingPolicyEnum_index = [...]uint8{0, 7, 12, 16, 20}
+
+func (i PaddingPolicyEnum) String() string {
+	if i < 0 || i >= PaddingPolicyEnum(len(_PaddingPolicyEnum_index)-1) {
+		return "PaddingPolicyEnum(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _PaddingPolicyEnum_name[_PaddingPolicyEnum_index[i]:_PaddingPolicyEnum_index[i+1]]
+}
@@ -0,0 +1,43 @@
+package patcher_test
+
+import (
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestRestorePadding(t *testing.T) {
+	t.Parallel()
+
+	if align := patcher.PaddingAlign(3 * 512); align != 512 {
+		t.Fatalf("align non valid: %d", align)
+	}
+
+	if align := patcher.PaddingAlign(1 << 20); align != patcher.MaxPaddingAlign {
+		t.Fatalf("align non valid: %d", align)
+	}
+
+	segment := patcher.Segment{Padding: 100, Align: 512}
+
+	tests := []struct {
+		policy   patcher.PaddingPolicyEnum
+		end      int64
+		expected int64
+	}{
+		{policy: patcher.PaddingPolicyAlign, end: 1000, expected: 24},
+		{policy: patcher.PaddingPolicyAlign, end: 1024, expected: 0},
+		{policy: patcher.PaddingPolicyUnknown, end: 1, expected: 511},
+		{policy: patcher.PaddingPolicyKeep, end: 1000, expected: 100},
+		{policy: patcher.PaddingPolicyDrop, end: 1000, expected: 0},
+	}
+
+	for _, test := range tests {
+		if padding := segment.RestorePadding(test.end, test.policy); padding != test.expected {
+			t.Fatalf("%s padding non valid: %d != %d", test.policy, padding, test.expected)
+		}
+	}
+
+	if padding := (patcher.Segment{Align: 512}).RestorePadding(1000, patcher.PaddingPolicyAlign); padding != 0 {
+		t.Fatalf("unpadded segment padding non valid: %d", padding)
+	}
+}
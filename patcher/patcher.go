@@ -0,0 +1,27 @@
+package patcher
+
+import "context"
+
+// Patcher applies patterns to a single file. Patch and PatchContext return
+// the result of the run, implementations may also send it to a result
+// channel given at construction. A failed run returns the error with a
+// result holding only the path and the error.
+type Patcher interface {
+	Patch(patterns []*Pattern, backup bool) (Result, error)
+	PatchContext(ctx context.Context, patterns []*Pattern, backup bool) (Result, error)
+}
+
+// Deliver completes a run of the patcher of path: a failed run gets a result
+// holding only the path and err. The result is also sent to results when it
+// is not nil, then returned with err.
+func Deliver(results chan<- Result, path string, result Result, err error) (Result, error) {
+	if err != nil {
+		result = NewError(path, err)
+	}
+
+	if results != nil {
+		results <- result
+	}
+
+	return result, err
+}
@@ -0,0 +1,70 @@
+package patcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=PatternStateEnum -linecomment -output pattern_state_enum_string.go
+type PatternStateEnum int
+
+const (
+	PatternStateUnknown   PatternStateEnum = iota // unknown
+	PatternStateUnpatched PatternStateEnum = iota // unpatched
+	PatternStatePatched   PatternStateEnum = iota // patched
+	PatternStateMismatch  PatternStateEnum = iota // mismatch
+)
+
+func (e *PatternStateEnum) SetValue(value string) error {
+	state := PatternStateFromString(value)
+	if state == PatternStateUnknown {
+		return &PatternStateValueError{
+			Value: value,
+		}
+	}
+
+	*e = state
+
+	return nil
+}
+
+func (e PatternStateEnum) MarshalText() ([]byte, error) {
+	if e == PatternStateUnknown {
+		return nil, &PatternStateValueError{
+			Value: PatternStateUnknown.String(),
+		}
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *PatternStateEnum) UnmarshalText(text []byte) error {
+	return e.SetValue(string(text))
+}
+
+func PatternStateFromString(value string) PatternStateEnum {
+	switch strings.ToLower(value) {
+	case "unpatched":
+		return PatternStateUnpatched
+	case "patched":
+		return PatternStatePatched
+	case "mismatch":
+		return PatternStateMismatch
+	default:
+		return PatternStateUnknown
+	}
+}
+
+type PatternStateValueError struct {
+	Value string
+}
+
+func (e *PatternStateValueError) Error() string {
+	return fmt.Sprintf("pattern state invalid value: %s", e.Value)
+}
+
+func (e *PatternStateValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=PatternStateEnum -linecomment -output pattern_state_enum_string.go"; DO NOT EDIT.
+
+package patcher
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[PatternStateUnknown-0]
+	_ = x[PatternStateUnpatched-1]
+	_ = x[PatternStatePatched-2]
+	_ = x[PatternStateMismatch-3]
+}
+
+const _PatternStateEnum_name = "unknownunpatchedpatchedmismatch"
+
+var _PatternStateEnum_index = [...]uint8{0, 7, 16, 23, 31}
+
+func (i PatternStateEnum) String() string {
+	if i < 0 || i >= PatternStateEnum(len(_PatternStateEnum_index)-1) {
+		return "PatternStateEnum(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _PatternStateEnum_name[_PatternStateEnum_index[i]:_PatternStateEnum_index[i+1]]
+}
@@ -0,0 +1,462 @@
+package patcher
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PatternSetFormatVersion is the only pattern set file format understood by LoadPatternSet.
+//
+// A pattern set is a YAML or JSON document:
+//
+//	format: 1                  # optional, defaults to 1
+//	name: disable-sig-check    # optional
+//	description: ...           # optional
+//	versions: ">=5.10, <6.2"   # optional, applies to every pattern
+//	patterns:
+//	  - description: skip module signature check
+//	    member: lib/modules/5.15.0/kernel/foo.ko   # optional
+//	    elf:                                       # optional, file or member is an ELF object
+//	      section: .text                           # optional, matches must be inside the section
+//	      symbol: check_sig                        # optional, match must be at the symbol address
+//	      offset: 0x10                             # optional, added to the symbol address
+//	    versions: ">=5.15"                         # optional
+//	    count: 1                                   # optional, defaults to 1
+//	    search: "48 8b ?? 10 e8"
+//	    replace: "90 90 ?? 90 90"
+//
+// Search and replace are hex strings, whitespace between bytes is ignored and
+// "??" marks a wildcard: any byte matches in search and the original byte is
+// kept in replace. Search and replace must have the same length.
+const PatternSetFormatVersion = 1
+
+var (
+	patternSetKeys = []string{"format", "name", "description", "versions", "patterns"}                  //nolint:gochecknoglobals
+	patternKeys    = []string{"description", "member", "elf", "versions", "count", "search", "replace"} //nolint:gochecknoglobals
+	elfAnchorKeys  = []string{"section", "symbol", "offset"}                                            //nolint:gochecknoglobals
+)
+
+type PatternSet struct {
+	Name        string
+	Description string
+	Versions    VersionConstraint
+	Patterns    []*Pattern
+}
+
+// Select returns the patterns whose version constraints hold for version.
+// An empty version is only accepted when no constraint is defined.
+func (s *PatternSet) Select(version string) ([]*Pattern, error) {
+	if len(version) == 0 {
+		if !s.Versions.IsEmpty() {
+			return nil, ErrPatternSetVersionRequired
+		}
+
+		for _, pattern := range s.Patterns {
+			if !pattern.Versions.IsEmpty() {
+				return nil, ErrPatternSetVersionRequired
+			}
+		}
+
+		return s.Patterns, nil
+	}
+
+	if ok, err := s.Versions.Check(version); err != nil || !ok {
+		return nil, err
+	}
+
+	patterns := make([]*Pattern, 0, len(s.Patterns))
+
+	for _, pattern := range s.Patterns {
+		ok, err := pattern.Versions.Check(version)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns, nil
+}
+
+func LoadPatternSet(path string) (*PatternSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pattern set failed: %w", err)
+	}
+
+	return ParsePatternSet(path, data)
+}
+
+// ParsePatternSet decodes a pattern set, source is used in error messages and
+// a ".json" extension enables strict JSON syntax checking.
+func ParsePatternSet(source string, data []byte) (*PatternSet, error) {
+	if strings.EqualFold(filepath.Ext(source), ".json") {
+		if err := checkJSON(source, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var root yaml.Node
+
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, &PatternSetError{Source: source, Err: fmt.Errorf("%w: %w", ErrPatternSetSyntax, err)}
+	}
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil, &PatternSetError{Source: source, Line: 1, Column: 1, Err: ErrPatternSetEmpty}
+	}
+
+	decoder := patternSetDecoder{source: source}
+
+	return decoder.decodeSet(root.Content[0])
+}
+
+// ParseHexPattern decodes a hex string where "??" marks a wildcard byte.
+// The returned mask is nil when there are no wildcards.
+func ParseHexPattern(value string) ([]byte, []byte, error) {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	if len(value)%2 != 0 {
+		return nil, nil, fmt.Errorf("%w: odd length", ErrPatternSetHex)
+	}
+
+	data := make([]byte, len(value)/2)
+	mask := bytes.Repeat([]byte{0xFF}, len(data))
+	wildcards := false
+
+	for ind := range data {
+		pair := value[ind*2 : ind*2+2]
+		if pair == "??" {
+			mask[ind] = 0x00
+			wildcards = true
+
+			continue
+		}
+
+		if _, err := hex.Decode(data[ind:ind+1], []byte(pair)); err != nil {
+			return nil, nil, fmt.Errorf("%w: byte %d %q", ErrPatternSetHex, ind, pair)
+		}
+	}
+
+	if !wildcards {
+		mask = nil
+	}
+
+	return data, mask, nil
+}
+
+func checkJSON(source string, data []byte) error {
+	var value interface{}
+
+	err := json.Unmarshal(data, &value)
+	if err == nil {
+		return nil
+	}
+
+	patternSetErr := &PatternSetError{Source: source, Err: fmt.Errorf("%w: %w", ErrPatternSetSyntax, err)}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		patternSetErr.Line, patternSetErr.Column = lineColumn(data, syntaxErr.Offset)
+	}
+
+	return patternSetErr
+}
+
+func lineColumn(data []byte, offset int64) (int, int) {
+	offset = min(offset, int64(len(data)))
+	line := bytes.Count(data[:offset], []byte{'\n'}) + 1
+	column := int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+
+	return line, column
+}
+
+type patternSetDecoder struct {
+	source string
+}
+
+func (d *patternSetDecoder) errorf(node *yaml.Node, field string, err error) error {
+	return &PatternSetError{
+		Source: d.source,
+		Line:   node.Line,
+		Column: node.Column,
+		Field:  field,
+		Err:    err,
+	}
+}
+
+func (d *patternSetDecoder) fields(node *yaml.Node, field string, known []string) (map[string]*yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, d.errorf(node, field, fmt.Errorf("%w: expected mapping", ErrPatternSetType))
+	}
+
+	fields := make(map[string]*yaml.Node, len(node.Content)/2)
+
+	for ind := 0; ind+1 < len(node.Content); ind += 2 {
+		key, value := node.Content[ind], node.Content[ind+1]
+		name := joinField(field, key.Value)
+
+		if !slices.Contains(known, key.Value) {
+			return nil, d.errorf(key, name, ErrPatternSetUnknownField)
+		}
+
+		if _, ok := fields[key.Value]; ok {
+			return nil, d.errorf(key, name, ErrPatternSetDuplicateField)
+		}
+
+		fields[key.Value] = value
+	}
+
+	return fields, nil
+}
+
+func (d *patternSetDecoder) decodeSet(node *yaml.Node) (*PatternSet, error) {
+	fields, err := d.fields(node, "", patternSetKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if format, ok := fields["format"]; ok {
+		var version int
+		if err := d.scalar(format, "format", &version); err != nil {
+			return nil, err
+		}
+
+		if version != PatternSetFormatVersion {
+			return nil, d.errorf(format, "format", fmt.Errorf("%w: %d", ErrPatternSetFormat, version))
+		}
+	}
+
+	set := &PatternSet{}
+
+	if err := d.optionalScalar(fields, "name", "name", &set.Name); err != nil {
+		return nil, err
+	}
+
+	if err := d.optionalScalar(fields, "description", "description", &set.Description); err != nil {
+		return nil, err
+	}
+
+	if set.Versions, err = d.versions(fields, "versions"); err != nil {
+		return nil, err
+	}
+
+	patterns, ok := fields["patterns"]
+	if !ok {
+		return nil, d.errorf(node, "patterns", ErrPatternSetFieldRequired)
+	}
+
+	if patterns.Kind != yaml.SequenceNode {
+		return nil, d.errorf(patterns, "patterns", fmt.Errorf("%w: expected sequence", ErrPatternSetType))
+	}
+
+	if len(patterns.Content) == 0 {
+		return nil, d.errorf(patterns, "patterns", ErrPatternSetEmpty)
+	}
+
+	set.Patterns = make([]*Pattern, 0, len(patterns.Content))
+
+	for ind, item := range patterns.Content {
+		pattern, err := d.decodePattern(item, fmt.Sprintf("patterns[%d]", ind))
+		if err != nil {
+			return nil, err
+		}
+
+		set.Patterns = append(set.Patterns, pattern)
+	}
+
+	return set, nil
+}
+
+func (d *patternSetDecoder) decodePattern(node *yaml.Node, field string) (*Pattern, error) {
+	fields, err := d.fields(node, field, patternKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := &Pattern{Count: 1}
+
+	if err := d.optionalScalar(fields, "description", joinField(field, "description"), &pattern.Description); err != nil {
+		return nil, err
+	}
+
+	if err := d.optionalScalar(fields, "member", joinField(field, "member"), &pattern.Member); err != nil {
+		return nil, err
+	}
+
+	if elfNode, ok := fields["elf"]; ok {
+		if pattern.ELF, err = d.decodeELFAnchor(elfNode, joinField(field, "elf")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.optionalScalar(fields, "count", joinField(field, "count"), &pattern.Count); err != nil {
+		return nil, err
+	}
+
+	if pattern.Count < 1 {
+		return nil, d.errorf(fields["count"], joinField(field, "count"), ErrPatternSetCount)
+	}
+
+	if pattern.Versions, err = d.versions(fields, joinField(field, "versions")); err != nil {
+		return nil, err
+	}
+
+	if pattern.Search, pattern.SearchMask, err = d.hex(node, fields, "search", field); err != nil {
+		return nil, err
+	}
+
+	if pattern.Replace, pattern.ReplaceMask, err = d.hex(node, fields, "replace", field); err != nil {
+		return nil, err
+	}
+
+	if err := pattern.CheckLength(); err != nil {
+		return nil, d.errorf(fields["replace"], joinField(field, "replace"), err)
+	}
+
+	return pattern, nil
+}
+
+func (d *patternSetDecoder) decodeELFAnchor(node *yaml.Node, field string) (*ELFAnchor, error) {
+	fields, err := d.fields(node, field, elfAnchorKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := &ELFAnchor{}
+
+	if err := d.optionalScalar(fields, "section", joinField(field, "section"), &anchor.Section); err != nil {
+		return nil, err
+	}
+
+	if err := d.optionalScalar(fields, "symbol", joinField(field, "symbol"), &anchor.Symbol); err != nil {
+		return nil, err
+	}
+
+	if err := d.optionalScalar(fields, "offset", joinField(field, "offset"), &anchor.Offset); err != nil {
+		return nil, err
+	}
+
+	if len(anchor.Section) == 0 && len(anchor.Symbol) == 0 {
+		return nil, d.errorf(node, field, fmt.Errorf("%w: section or symbol", ErrPatternSetFieldRequired))
+	}
+
+	if len(anchor.Symbol) == 0 && anchor.Offset != 0 {
+		return nil, d.errorf(fields["offset"], joinField(field, "offset"), fmt.Errorf(
+			"%w: symbol", ErrPatternSetFieldRequired,
+		))
+	}
+
+	return anchor, nil
+}
+
+func (d *patternSetDecoder) hex(node *yaml.Node, fields map[string]*yaml.Node, key, parent string) ([]byte, []byte, error) {
+	field := joinField(parent, key)
+
+	valueNode, ok := fields[key]
+	if !ok {
+		return nil, nil, d.errorf(node, field, ErrPatternSetFieldRequired)
+	}
+
+	var value string
+	if err := d.scalar(valueNode, field, &value); err != nil {
+		return nil, nil, err
+	}
+
+	data, mask, err := ParseHexPattern(value)
+	if err != nil {
+		return nil, nil, d.errorf(valueNode, field, err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil, d.errorf(valueNode, field, ErrPatternSetEmpty)
+	}
+
+	return data, mask, nil
+}
+
+func (d *patternSetDecoder) versions(fields map[string]*yaml.Node, field string) (VersionConstraint, error) {
+	var value string
+	if err := d.optionalScalar(fields, "versions", field, &value); err != nil {
+		return VersionConstraint{}, err
+	}
+
+	constraint, err := ParseVersionConstraint(value)
+	if err != nil {
+		return VersionConstraint{}, d.errorf(fields["versions"], field, err)
+	}
+
+	return constraint, nil
+}
+
+func (d *patternSetDecoder) optionalScalar(fields map[string]*yaml.Node, key, field string, value interface{}) error {
+	node, ok := fields[key]
+	if !ok {
+		return nil
+	}
+
+	return d.scalar(node, field, value)
+}
+
+func (d *patternSetDecoder) scalar(node *yaml.Node, field string, value interface{}) error {
+	if node.Kind != yaml.ScalarNode {
+		return d.errorf(node, field, fmt.Errorf("%w: expected scalar", ErrPatternSetType))
+	}
+
+	if err := node.Decode(value); err != nil {
+		return d.errorf(node, field, fmt.Errorf("%w: %w", ErrPatternSetType, err))
+	}
+
+	return nil
+}
+
+func joinField(parent, key string) string {
+	if len(parent) == 0 {
+		return key
+	}
+
+	return parent + "." + key
+}
+
+type PatternSetError struct {
+	Source string
+	Line   int
+	Column int
+	Field  string
+	Err    error
+}
+
+func (e *PatternSetError) Error() string {
+	location := e.Source
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", e.Source, e.Line, e.Column)
+	}
+
+	if len(e.Field) > 0 {
+		return fmt.Sprintf("%s: %s: %v", location, e.Field, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %v", location, e.Err)
+}
+
+func (e *PatternSetError) Unwrap() error {
+	return e.Err
+}
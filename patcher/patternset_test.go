@@ -0,0 +1,149 @@
+package patcher_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestLoadPatternSet(t *testing.T) {
+	t.Parallel()
+
+	set, err := patcher.LoadPatternSet("testdata/patterns.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(set.Patterns) != 2 {
+		t.Fatalf("patterns len non valid: %d", len(set.Patterns))
+	}
+
+	wildcard := set.Patterns[1]
+	if wildcard.Count != 2 || wildcard.Member != "lib/modules/5.15.0/kernel/foo.ko" {
+		t.Fatalf("pattern fields non valid: %+v", wildcard)
+	}
+
+	if anchor := wildcard.ELF; anchor == nil || anchor.Section != ".text" || anchor.Symbol != "check_sig" ||
+		anchor.Offset != 0x10 {
+		t.Fatalf("pattern elf anchor non valid: %+v", anchor)
+	}
+
+	if !bytes.Equal(wildcard.SearchMask, []byte{0xFF, 0xFF, 0x00, 0xFF}) {
+		t.Fatalf("search mask non valid: %x", wildcard.SearchMask)
+	}
+
+	selected, err := set.Select("5.10.0-21-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(selected) != 1 {
+		t.Fatalf("selected len non valid: %d", len(selected))
+	}
+
+	if _, err := set.Select(""); !errors.Is(err, patcher.ErrPatternSetVersionRequired) {
+		t.Fatalf("expected version required, got %v", err)
+	}
+
+	jsonSet, err := patcher.LoadPatternSet("testdata/patterns.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(jsonSet.Patterns[0].Replace, []byte{0xCA, 0xFE, 0xBA, 0xBE}) {
+		t.Fatalf("replace non valid: %x", jsonSet.Patterns[0].Replace)
+	}
+}
+
+func TestParsePatternSetErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		source string
+		data   string
+		err    error
+		line   int
+		field  string
+	}{
+		{
+			name:   "unknown field",
+			source: "set.yaml",
+			data:   "patterns:\n  - search: aa\n    replace: bb\n    size: 1\n",
+			err:    patcher.ErrPatternSetUnknownField,
+			line:   4,
+			field:  "patterns[0].size",
+		},
+		{
+			name:   "invalid hex",
+			source: "set.yaml",
+			data:   "patterns:\n  - search: aa\n    replace: zz\n",
+			err:    patcher.ErrPatternSetHex,
+			line:   3,
+			field:  "patterns[0].replace",
+		},
+		{
+			name:   "length mismatch",
+			source: "set.yaml",
+			data:   "patterns:\n  - search: aabb\n    replace: bb\n",
+			err:    patcher.ErrPatternSetLength,
+			line:   3,
+			field:  "patterns[0].replace",
+		},
+		{
+			name:   "missing search",
+			source: "set.yaml",
+			data:   "patterns:\n  - replace: bb\n",
+			err:    patcher.ErrPatternSetFieldRequired,
+			line:   2,
+			field:  "patterns[0].search",
+		},
+		{
+			name:   "invalid count",
+			source: "set.yaml",
+			data:   "patterns:\n  - search: aa\n    replace: bb\n    count: 0\n",
+			err:    patcher.ErrPatternSetCount,
+			line:   4,
+			field:  "patterns[0].count",
+		},
+		{
+			name:   "elf offset without symbol",
+			source: "set.yaml",
+			data:   "patterns:\n  - search: aa\n    replace: bb\n    elf:\n      section: .text\n      offset: 4\n",
+			err:    patcher.ErrPatternSetFieldRequired,
+			line:   6,
+			field:  "patterns[0].elf.offset",
+		},
+		{
+			name:   "json syntax",
+			source: "set.json",
+			data:   "{\n  \"patterns\": [\n    {\"search\": \"aa\",}\n  ]\n}\n",
+			err:    patcher.ErrPatternSetSyntax,
+			line:   3,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := patcher.ParsePatternSet(test.source, []byte(test.data))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+
+			var setErr *patcher.PatternSetError
+			if !errors.As(err, &setErr) {
+				t.Fatalf("expected PatternSetError, got %T", err)
+			}
+
+			if setErr.Line != test.line || setErr.Field != test.field {
+				t.Fatalf("location non valid: %s", setErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package patcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=PhaseEnum -linecomment -output phase_enum_string.go
+type PhaseEnum int
+
+const (
+	PhaseUnknown PhaseEnum = iota // unknown
+	PhaseCut     PhaseEnum = iota // cut
+	PhaseCopy    PhaseEnum = iota // copy
+	PhaseUnpack  PhaseEnum = iota // unpack
+	PhaseSearch  PhaseEnum = iota // search
+	PhasePatch   PhaseEnum = iota // patch
+	PhaseBackup  PhaseEnum = iota // backup
+	PhasePack    PhaseEnum = iota // pack
+)
+
+func (e *PhaseEnum) SetValue(value string) error {
+	phase := PhaseFromString(value)
+	if phase == PhaseUnknown {
+		return &PhaseValueError{
+			Value: value,
+		}
+	}
+
+	*e = phase
+
+	return nil
+}
+
+func (e PhaseEnum) MarshalText() ([]byte, error) {
+	if e == PhaseUnknown {
+		return nil, &PhaseValueError{
+			Value: PhaseUnknown.String(),
+		}
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *PhaseEnum) UnmarshalText(text []byte) error {
+	return e.SetValue(string(text))
+}
+
+func PhaseFromString(value string) PhaseEnum {
+	switch strings.ToLower(value) {
+	case "cut":
+		return PhaseCut
+	case "copy":
+		return PhaseCopy
+	case "unpack":
+		return PhaseUnpack
+	case "search":
+		return PhaseSearch
+	case "patch":
+		return PhasePatch
+	case "backup":
+		return PhaseBackup
+	case "pack":
+		return PhasePack
+	default:
+		return PhaseUnknown
+	}
+}
+
+type PhaseValueError struct {
+	Value string
+}
+
+func (e *PhaseValueError) Error() string {
+	return fmt.Sprintf("phase invalid value: %s", e.Value)
+}
+
+func (e *PhaseValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
@@ -0,0 +1,30 @@
+// Code generated by "stringer -type=PhaseEnum -linecomment -output phase_enum_string.go"; DO NOT EDIT.
+
+package patcher
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[PhaseUnknown-0]
+	_ = x[PhaseCut-1]
+	_ = x[PhaseCopy-2]
+	_ = x[PhaseUnpack-3]
+	_ = x[PhaseSearch-4]
+	_ = x[PhasePatch-5]
+	_ = x[PhaseBackup-6]
+	_ = x[PhasePack-7]
+}
+
+const _PhaseEnum_name = "unknowncutcopyunpacksearchpatchbackuppack"
+
+var _PhaseEnum_index = [...]uint8{0, 7, 10, 14, 20, 26, 31, 37, 41}
+
+func (i PhaseEnum) String() string {
+	if i < 0 || i >= PhaseEnum(len(_PhaseEnum_index)-1) {
+		return "PhaseEnum(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _PhaseEnum_name[_PhaseEnum_index[i]:_PhaseEnum_index[i+1]]
+}
@@ -0,0 +1,56 @@
+package patcher
+
+import (
+	"context"
+	"io"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+// Progress reports how far a patcher got in the current phase.
+// Total is zero when the size of the phase input is not known upfront.
+type Progress struct {
+	Path         string
+	Phase        PhaseEnum
+	PatternIndex int
+	Bytes        int64
+	Total        int64
+}
+
+type ProgressFunc func(Progress)
+
+// Report calls fn unless it is nil.
+func (fn ProgressFunc) Report(path string, phase PhaseEnum, patternIndex int, processed, total int64) {
+	if fn == nil {
+		return
+	}
+
+	fn(Progress{
+		Path:         path,
+		Phase:        phase,
+		PatternIndex: patternIndex,
+		Bytes:        processed,
+		Total:        total,
+	})
+}
+
+// Reader wraps reader so it stops once ctx is done and reports the bytes read through fn.
+func (fn ProgressFunc) Reader(
+	ctx context.Context,
+	reader io.Reader,
+	path string,
+	phase PhaseEnum,
+	patternIndex int,
+	total int64,
+) io.Reader {
+	ctxReader := libio.NewContextReader(ctx, reader)
+	if fn == nil {
+		return ctxReader
+	}
+
+	fn.Report(path, phase, patternIndex, 0, total)
+
+	return libio.NewCountingReader(ctxReader, func(processed int64) {
+		fn.Report(path, phase, patternIndex, processed, total)
+	})
+}
@@ -0,0 +1,52 @@
+package patcher_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestProgressReader(t *testing.T) {
+	t.Parallel()
+
+	reports := make([]patcher.Progress, 0)
+	progress := patcher.ProgressFunc(func(p patcher.Progress) {
+		reports = append(reports, p)
+	})
+
+	reader := progress.Reader(context.Background(), strings.NewReader("abcd"), "img", patcher.PhaseSearch, 1, 4)
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("reports non valid: %+v", reports)
+	}
+
+	first, last := reports[0], reports[len(reports)-1]
+	if first.Bytes != 0 || last.Bytes != 4 || last.Total != 4 {
+		t.Fatalf("reports non valid: %+v", reports)
+	}
+
+	if last.Path != "img" || last.Phase != patcher.PhaseSearch || last.PatternIndex != 1 {
+		t.Fatalf("report non valid: %+v", last)
+	}
+}
+
+func TestProgressReaderCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var progress patcher.ProgressFunc
+
+	reader := progress.Reader(ctx, strings.NewReader("abcd"), "img", patcher.PhaseSearch, 0, 4)
+	if _, err := io.Copy(io.Discard, reader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+}
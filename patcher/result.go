@@ -0,0 +1,160 @@
+package patcher
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/grinderz/grgo/libio"
+)
+
+// Result describes a patched file. Signed lists the patched kernel modules,
+// archive members or the file itself, whose appended signature was
+// invalidated, or stripped or replaced by the signature policy. Output is
+// only set when the file was rewritten, SignaturePath when a detached
+// signature of the output was written.
+type Result struct {
+	Path          string          `json:"path"`
+	BytesPatched  int             `json:"bytesPatched"`
+	Signed        []string        `json:"signed,omitempty"`
+	Patterns      []PatternResult `json:"patterns,omitempty"`
+	Backup        string          `json:"backup,omitempty"`
+	SignaturePath string          `json:"signaturePath,omitempty"`
+	Segments      []Segment       `json:"segments,omitempty"`
+	Input         FileInfo        `json:"input"`
+	Output        *FileInfo       `json:"output,omitempty"`
+	Phases        []PhaseTime     `json:"phases,omitempty"`
+	Err           error           `json:"-"`
+}
+
+func NewResult(path string, bytesPatched int) Result {
+	return Result{Path: path, BytesPatched: bytesPatched}
+}
+
+func NewError(path string, err error) Result {
+	return Result{Path: path, Err: err}
+}
+
+// MarshalJSON encodes Err as its message.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+
+	var message string
+	if r.Err != nil {
+		message = r.Err.Error()
+	}
+
+	data, err := json.Marshal(struct {
+		result
+		Error string `json:"error,omitempty"`
+	}{result(r), message})
+	if err != nil {
+		return nil, fmt.Errorf("result marshal failed: %w", err)
+	}
+
+	return data, nil
+}
+
+// PatternResult lists the offsets replaced by a pattern, relative to the
+// patched payload: the decompressed archive or the file itself.
+type PatternResult struct {
+	Index       int     `json:"index"`
+	Description string  `json:"description,omitempty"`
+	Offsets     []int64 `json:"offsets"`
+}
+
+// Segment is a part of the input file such as a cpio header or a
+// compressed payload. Streams lists the gzip members or xz streams of a
+// compressed payload, their offsets are relative to the segment. Padding
+// counts the zero bytes following the segment, not included in Size, and
+// Align the alignment of the padded segment end.
+type Segment struct {
+	Name    string         `json:"name"`
+	Offset  int64          `json:"offset"`
+	Size    int64          `json:"size"`
+	Padding int64          `json:"padding,omitempty"`
+	Align   int64          `json:"align,omitempty"`
+	Streams []libio.Stream `json:"streams,omitempty"`
+}
+
+type FileInfo struct {
+	Size        int64  `json:"size"`
+	Compression string `json:"compression,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	SHA512      string `json:"sha512,omitempty"`
+}
+
+// HashReader returns the size, SHA-256 and SHA-512 of the data read from reader.
+func HashReader(reader io.Reader) (FileInfo, error) {
+	hasher256 := sha256.New()
+	hasher512 := sha512.New()
+
+	size, err := io.Copy(io.MultiWriter(hasher256, hasher512), reader)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("hash copy failed: %w", err)
+	}
+
+	return FileInfo{
+		Size:   size,
+		SHA256: hex.EncodeToString(hasher256.Sum(nil)),
+		SHA512: hex.EncodeToString(hasher512.Sum(nil)),
+	}, nil
+}
+
+type PhaseTime struct {
+	Phase   PhaseEnum     `json:"phase"`
+	Elapsed time.Duration `json:"elapsedNs"`
+}
+
+// PhaseTimer accumulates the time spent in each phase, a phase lasts until
+// the next one starts. Starting the running phase again has no effect.
+type PhaseTimer struct {
+	phases  []PhaseTime
+	current int
+	start   time.Time
+}
+
+func (t *PhaseTimer) Start(phase PhaseEnum) {
+	if t == nil {
+		return
+	}
+
+	now := time.Now()
+
+	if len(t.phases) > 0 {
+		if t.phases[t.current].Phase == phase {
+			return
+		}
+
+		t.phases[t.current].Elapsed += now.Sub(t.start)
+	}
+
+	t.start = now
+	t.current = len(t.phases)
+
+	for ind, phaseTime := range t.phases {
+		if phaseTime.Phase == phase {
+			t.current = ind
+			return
+		}
+	}
+
+	t.phases = append(t.phases, PhaseTime{Phase: phase})
+}
+
+// Phases stops the running phase and returns the elapsed time of every
+// phase in the order they first started.
+func (t *PhaseTimer) Phases() []PhaseTime {
+	if t == nil || len(t.phases) == 0 {
+		return nil
+	}
+
+	t.phases[t.current].Elapsed += time.Since(t.start)
+	t.start = time.Now()
+
+	return append([]PhaseTime(nil), t.phases...)
+}
@@ -0,0 +1,86 @@
+package patcher_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestResultJSON(t *testing.T) {
+	t.Parallel()
+
+	timer := &patcher.PhaseTimer{}
+	timer.Start(patcher.PhaseUnpack)
+	timer.Start(patcher.PhaseSearch)
+	timer.Start(patcher.PhaseUnpack)
+	time.Sleep(time.Millisecond)
+
+	result := patcher.NewResult("initrd.img", 4)
+	result.Patterns = []patcher.PatternResult{{Index: 0, Offsets: []int64{16}}}
+	result.Phases = timer.Phases()
+	result.Err = errors.New("boom")
+
+	if len(result.Phases) != 2 || result.Phases[0].Phase != patcher.PhaseUnpack || result.Phases[0].Elapsed == 0 {
+		t.Fatalf("phases non valid: %+v", result.Phases)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`"path":"initrd.img"`,
+		`"bytesPatched":4`,
+		`"offsets":[16]`,
+		`"phase":"unpack"`,
+		`"error":"boom"`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Fatalf("json non valid, %s not found: %s", expected, data)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("run failed")
+
+	tests := []struct {
+		name   string
+		result patcher.Result
+		err    error
+		want   patcher.Result
+	}{
+		{"success", patcher.NewResult("a", 3), nil, patcher.NewResult("a", 3)},
+		{"failure", patcher.NewResult("", 3), errRun, patcher.NewError("a", errRun)},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := make(chan patcher.Result, 1)
+
+			result, err := patcher.Deliver(results, "a", test.result, test.err)
+			if !errors.Is(err, test.err) || result.Path != test.want.Path || result.BytesPatched != test.want.BytesPatched || !errors.Is(result.Err, test.want.Err) {
+				t.Fatalf("result non valid: %+v %v", result, err)
+			}
+
+			if sent := <-results; sent.Path != result.Path || sent.BytesPatched != result.BytesPatched || !errors.Is(sent.Err, result.Err) {
+				t.Fatalf("sent result non valid: %+v", sent)
+			}
+
+			if _, err := patcher.Deliver(nil, "a", test.result, test.err); !errors.Is(err, test.err) {
+				t.Fatalf("nil channel error non valid: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package patcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=SignaturePolicyEnum -linecomment -output signature_policy_enum_string.go
+type SignaturePolicyEnum int
+
+const (
+	SignaturePolicyUnknown SignaturePolicyEnum = iota // unknown
+	SignaturePolicyKeep    SignaturePolicyEnum = iota // keep
+	SignaturePolicyStrip   SignaturePolicyEnum = iota // strip
+	SignaturePolicyResign  SignaturePolicyEnum = iota // resign
+)
+
+func (e *SignaturePolicyEnum) SetValue(value string) error {
+	mode := SignaturePolicyFromString(value)
+	if mode == SignaturePolicyUnknown {
+		return &SignaturePolicyValueError{
+			Value: value,
+		}
+	}
+
+	*e = mode
+
+	return nil
+}
+
+func (e SignaturePolicyEnum) MarshalText() ([]byte, error) {
+	if e == SignaturePolicyUnknown {
+		return nil, &SignaturePolicyValueError{
+			Value: SignaturePolicyUnknown.String(),
+		}
+	}
+
+	return []byte(e.String()), nil
+}
+
+func (e *SignaturePolicyEnum) UnmarshalText(text []byte) error {
+	return e.SetValue(string(text))
+}
+
+func SignaturePolicyFromString(value string) SignaturePolicyEnum {
+	switch strings.ToLower(value) {
+	case "keep":
+		return SignaturePolicyKeep
+	case "strip":
+		return SignaturePolicyStrip
+	case "resign":
+		return SignaturePolicyResign
+	default:
+		return SignaturePolicyUnknown
+	}
+}
+
+type SignaturePolicyValueError struct {
+	Value string
+}
+
+func (e *SignaturePolicyValueError) Error() string {
+	return fmt.Sprintf("signature policy invalid value: %s", e.Value)
+}
+
+func (e *SignaturePolicyValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
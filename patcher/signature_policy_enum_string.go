@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=SignaturePolicyEnum -linecomment -output signature_policy_enum_string.go"; DO NOT EDIT.
+
+package patcher
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SignaturePolicyUnknown-0]
+	_ = x[SignaturePolicyKeep-1]
+	_ = x[SignaturePolicyStrip-2]
+	_ = x[SignaturePolicyResign-3]
+}
+
+const _SignaturePolicyEnum_name = "unknownkeepstripresign"
+
+var _SignaturePolicyEnum_index = [...]uint8{0, 7, 11, 16, 22}
+
+func (i SignaturePolicyEnum) String() string {
+	if i < 0 || i >= SignaturePolicyEnum(len(_SignaturePolicyEnum_index)-1) {
+		return "SignaturePolicyEnum(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _SignaturePolicyEnum_name[_SignaturePolicyEnum_index[i]:_SignaturePolicyEnum_index[i+1]]
+}
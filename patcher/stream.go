@@ -0,0 +1,152 @@
+package patcher
+
+import (
+	"fmt"
+	"io"
+)
+
+// ReplaceReader applies patterns to the stream read from an underlying reader.
+// Besides the read buffer only the sum of the pattern lengths minus one bytes
+// are held back, so memory does not depend on the stream size. A pattern only
+// scans bytes the previous patterns are done with, so the output is the one of
+// applying the patterns in order to the whole stream whatever the buffer
+// size, matches of a pattern never overlap.
+// Member and ELF restrictions need random access and are not supported.
+type ReplaceReader struct {
+	reader   io.Reader
+	patterns []*Pattern
+	offsets  [][]int64
+	next     []int64
+	keep     int
+	buff     []byte
+	window   []byte
+	done     int
+	out      []byte
+	offset   int64
+	replaced int
+	err      error
+}
+
+func NewReplaceReader(reader io.Reader, patterns []*Pattern, buffSize int) (*ReplaceReader, error) {
+	keep := 0
+
+	for ind, pattern := range patterns {
+		if len(pattern.Member) > 0 || pattern.ELF != nil {
+			return nil, fmt.Errorf("pattern %d: %w", ind, ErrPatternStreamUnsupported)
+		}
+
+		if err := pattern.CheckLength(); err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", ind, err)
+		}
+
+		keep += max(len(pattern.Search)-1, 0)
+	}
+
+	return &ReplaceReader{
+		reader:   reader,
+		patterns: patterns,
+		offsets:  make([][]int64, len(patterns)),
+		next:     make([]int64, len(patterns)),
+		keep:     keep,
+		buff:     make([]byte, buffSize),
+		window:   make([]byte, 0, buffSize+keep),
+	}, nil
+}
+
+func (r *ReplaceReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		r.fill()
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// fill reads the next chunk and runs the patterns over it in order. Every
+// pattern scans up to where the previous one is done, the bytes all patterns
+// are done with are exposed as output and the rest is held back.
+func (r *ReplaceReader) fill() {
+	if r.done > 0 && r.err == nil {
+		r.offset += int64(r.done)
+		r.window = append(r.window[:0], r.window[r.done:]...)
+	}
+
+	read, err := r.reader.Read(r.buff)
+	r.window = append(r.window, r.buff[:read]...)
+
+	if err != nil {
+		r.err = err
+		if err != io.EOF {
+			r.err = fmt.Errorf("replace reader read failed: %w", err)
+		}
+	}
+
+	end := len(r.window)
+
+	for ind, pattern := range r.patterns {
+		r.replace(ind, pattern, end)
+
+		if r.err == nil {
+			end = max(end-max(len(pattern.Search)-1, 0), 0)
+		}
+	}
+
+	r.done = end
+	r.out = r.window[:end]
+
+	if r.err != nil {
+		r.offset += int64(len(r.window))
+		r.window = r.window[:0]
+		r.done = 0
+	}
+}
+
+// replace replaces the matches of the pattern at index lying before end of
+// the window. No match of it can start before end minus its length plus
+// one anymore, the next scan resumes from there.
+func (r *ReplaceReader) replace(index int, pattern *Pattern, end int) {
+	findLen := len(pattern.Search)
+	if findLen == 0 {
+		return
+	}
+
+	ind := int(max(r.next[index]-r.offset, 0))
+
+	for ind+findLen <= end {
+		next := indexMask(r.window[ind:end], pattern.Search, pattern.SearchMask)
+		if next < 0 {
+			break
+		}
+
+		ind += next
+
+		match := r.window[ind : ind+findLen]
+		if pattern.ReplaceMask == nil {
+			copy(match, pattern.Replace)
+		} else {
+			applyMask(match, pattern.Replace, pattern.ReplaceMask)
+		}
+
+		r.offsets[index] = append(r.offsets[index], r.offset+int64(ind))
+		r.replaced += findLen
+		ind += findLen
+	}
+
+	r.next[index] = r.offset + int64(max(ind, end-findLen+1))
+}
+
+// Offsets returns the stream offsets replaced by the pattern at index so far.
+func (r *ReplaceReader) Offsets(index int) []int64 {
+	return r.offsets[index]
+}
+
+// Replaced returns the number of bytes replaced so far.
+func (r *ReplaceReader) Replaced() int {
+	return r.replaced
+}
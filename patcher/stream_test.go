@@ -0,0 +1,126 @@
+package patcher_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestReplaceReader(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("..HELLO_WORLD.x1y2.."), 50)
+	expected := bytes.ReplaceAll(bytes.ReplaceAll(data, []byte("HELLO_WORLD"), []byte("HELLO_THERE")),
+		[]byte("x1y2"), []byte("x9y9"))
+
+	patterns := []*patcher.Pattern{
+		{Count: 50, Search: []byte("HELLO_WORLD"), Replace: []byte("HELLO_THERE")},
+		{
+			Count:       50,
+			Search:      []byte("x?y?"),
+			SearchMask:  []byte{0xFF, 0x00, 0xFF, 0x00},
+			Replace:     []byte{0, '9', 0, '9'},
+			ReplaceMask: []byte{0x00, 0xFF, 0x00, 0xFF},
+		},
+	}
+
+	for _, buffSize := range []int{1, 3, 7, 64, 4096} {
+		reader, err := patcher.NewReplaceReader(bytes.NewReader(data), patterns, buffSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(out, expected) {
+			t.Fatalf("buffer %d output non valid: %q", buffSize, out)
+		}
+
+		offsets, err := patcher.SearchBytes(bytes.NewReader(data), []byte("HELLO_WORLD"), 64, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(reader.Offsets(0), offsets) || len(reader.Offsets(1)) != 50 {
+			t.Fatalf("buffer %d offsets non valid: %v", buffSize, reader.Offsets(0))
+		}
+
+		if reader.Replaced() != 50*(11+4) {
+			t.Fatalf("buffer %d replaced non valid: %d", buffSize, reader.Replaced())
+		}
+	}
+}
+
+func TestReplaceReaderChained(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	data := make([]byte, 4000)
+
+	for ind := range data {
+		data[ind] = "ABY"[rnd.Intn(3)]
+	}
+
+	// Each pattern rewrites bytes the next one searches, so the output only
+	// holds when a pattern never scans bytes the previous one may still change.
+	patterns := []*patcher.Pattern{
+		{Search: []byte("ABA"), Replace: []byte("YAB")},
+		{Search: []byte("BAY"), Replace: []byte("ABA")},
+		{Search: []byte("YY"), Replace: []byte("BA")},
+	}
+
+	expected := slices.Clone(data)
+	for _, pattern := range patterns {
+		expected = bytes.ReplaceAll(expected, pattern.Search, pattern.Replace)
+	}
+
+	for buffSize := 1; buffSize <= 16; buffSize++ {
+		reader, err := patcher.NewReplaceReader(bytes.NewReader(data), patterns, buffSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(out, expected) {
+			t.Fatalf("buffer %d output non valid", buffSize)
+		}
+	}
+}
+
+func TestReplaceReaderLength(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		pattern *patcher.Pattern
+	}{
+		{"replace", &patcher.Pattern{Search: []byte("abc"), Replace: []byte("ab")}},
+		{"search mask", &patcher.Pattern{Search: []byte("abc"), SearchMask: []byte{0xFF}, Replace: []byte("abd")}},
+		{"replace mask", &patcher.Pattern{Search: []byte("abc"), Replace: []byte("abd"), ReplaceMask: []byte{0xFF}}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := patcher.NewReplaceReader(bytes.NewReader(nil), []*patcher.Pattern{test.pattern}, 64)
+			if !errors.Is(err, patcher.ErrPatternSetLength) {
+				t.Fatalf("expected length error, got %v", err)
+			}
+		})
+	}
+}
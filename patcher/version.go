@@ -0,0 +1,185 @@
+package patcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grinderz/grgo/liberr"
+)
+
+type versionCondition struct {
+	operator string
+	version  []int
+}
+
+// VersionConstraint is a comma separated list of conditions like ">=5.10, <6.2"
+// that must all hold. Versions are compared by their leading dot separated
+// numbers, so "5.15.0-91-generic" compares as 5.15.0. The zero value matches
+// any version.
+type VersionConstraint struct {
+	raw        string
+	conditions []versionCondition
+}
+
+func ParseVersionConstraint(value string) (VersionConstraint, error) {
+	constraint := VersionConstraint{raw: strings.TrimSpace(value)}
+
+	if len(constraint.raw) == 0 {
+		return constraint, nil
+	}
+
+	for _, part := range strings.Split(constraint.raw, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			return VersionConstraint{}, &VersionConstraintError{Value: value}
+		}
+
+		rest := strings.TrimLeft(part, "<>=!")
+		operator := part[:len(part)-len(rest)]
+
+		switch operator {
+		case "":
+			operator = "="
+		case "=", "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return VersionConstraint{}, &VersionConstraintError{Value: value}
+		}
+
+		version, ok := parseVersion(strings.TrimSpace(rest))
+		if !ok {
+			return VersionConstraint{}, &VersionConstraintError{Value: value}
+		}
+
+		constraint.conditions = append(constraint.conditions, versionCondition{
+			operator: operator,
+			version:  version,
+		})
+	}
+
+	return constraint, nil
+}
+
+func (c VersionConstraint) IsEmpty() bool {
+	return len(c.conditions) == 0
+}
+
+func (c VersionConstraint) String() string {
+	return c.raw
+}
+
+func (c VersionConstraint) Check(version string) (bool, error) {
+	if c.IsEmpty() {
+		return true, nil
+	}
+
+	parsed, ok := parseVersion(version)
+	if !ok {
+		return false, &VersionValueError{Value: version}
+	}
+
+	for _, condition := range c.conditions {
+		if !condition.check(parsed) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (c versionCondition) check(version []int) bool {
+	cmp := compareVersions(version, c.version)
+
+	switch c.operator {
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return cmp == 0
+	}
+}
+
+func parseVersion(value string) ([]int, bool) {
+	value = strings.TrimPrefix(value, "v")
+
+	end := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end >= 0 {
+		value = value[:end]
+	}
+
+	value = strings.TrimSuffix(value, ".")
+	if len(value) == 0 {
+		return nil, false
+	}
+
+	parts := strings.Split(value, ".")
+	version := make([]int, 0, len(parts))
+
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+
+		version = append(version, number)
+	}
+
+	return version, true
+}
+
+func compareVersions(left, right []int) int {
+	for ind := 0; ind < max(len(left), len(right)); ind++ {
+		var l, r int
+
+		if ind < len(left) {
+			l = left[ind]
+		}
+
+		if ind < len(right) {
+			r = right[ind]
+		}
+
+		if l != r {
+			if l < r {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+type VersionConstraintError struct {
+	Value string
+}
+
+func (e *VersionConstraintError) Error() string {
+	return fmt.Sprintf("version constraint invalid value: %s", e.Value)
+}
+
+func (e *VersionConstraintError) Unwrap() error {
+	return liberr.ErrInvalid
+}
+
+type VersionValueError struct {
+	Value string
+}
+
+func (e *VersionValueError) Error() string {
+	return fmt.Sprintf("version invalid value: %s", e.Value)
+}
+
+func (e *VersionValueError) Unwrap() error {
+	return liberr.ErrInvalid
+}
@@ -0,0 +1,64 @@
+package patcher_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/grinderz/grgo/patcher"
+)
+
+func TestParseVersionConstraint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		version string
+		match   bool
+		err     bool
+	}{
+		{name: "empty", value: "", version: "1.0", match: true},
+		{name: "bare version", value: "5.15", version: "5.15.0-91-generic", match: true},
+		{name: "bare version mismatch", value: "5.15", version: "5.16", match: false},
+		{name: "operator", value: ">=5.10", version: "5.15", match: true},
+		{name: "whitespace", value: "  >= 5.10 ,  < 6.2  ", version: "6.1", match: true},
+		{name: "whitespace upper bound", value: "  >= 5.10 ,  < 6.2  ", version: "6.2", match: false},
+		{name: "not equal", value: "!=5.15", version: "5.15", match: false},
+		{name: "trailing comma", value: ">=5.10,", err: true},
+		{name: "leading comma", value: ",>=5.10", err: true},
+		{name: "empty part", value: ">=5.10, ,<6.2", err: true},
+		{name: "unknown operator", value: "=>5.10", err: true},
+		{name: "missing version", value: ">=", err: true},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			constraint, err := patcher.ParseVersionConstraint(test.value)
+			if test.err {
+				var constraintErr *patcher.VersionConstraintError
+				if !errors.As(err, &constraintErr) {
+					t.Fatalf("expected VersionConstraintError, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			match, err := constraint.Check(test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if match != test.match {
+				t.Fatalf("match non valid: %t", match)
+			}
+		})
+	}
+}
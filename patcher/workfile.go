@@ -0,0 +1,105 @@
+package patcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grinderz/grgo/libfs"
+)
+
+// WorkFile is a temp file next to the file it replaces. Commit renames it
+// over the file, so readers see either the original or the complete new
+// content.
+type WorkFile struct {
+	libfs.File
+	fsys      libfs.FS
+	path      string
+	source    string
+	name      string
+	committed bool
+}
+
+// CreateWorkFile creates a work file replacing path, its name ends with
+// suffix. Symlinks in path are resolved first, so a symlinked file is
+// replaced and the symlink kept.
+func CreateWorkFile(fsys libfs.FS, path, suffix string) (*WorkFile, error) {
+	target, err := fsys.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve symlinks failed: %w", err)
+	}
+
+	return CreateWorkFileAs(fsys, target, target, suffix)
+}
+
+// CreateWorkFileAs creates a work file committed as path, which may not
+// exist yet, with the permissions and owner of the file source.
+func CreateWorkFileAs(fsys libfs.FS, path, source, suffix string) (*WorkFile, error) {
+	file, name, err := libfs.CreateTemp(fsys, filepath.Dir(path), "."+filepath.Base(path)+".*"+suffix)
+	if err != nil {
+		return nil, fmt.Errorf("create work file failed: %w", err)
+	}
+
+	return &WorkFile{
+		File:   file,
+		fsys:   fsys,
+		path:   path,
+		source: source,
+		name:   name,
+	}, nil
+}
+
+// Name returns the path of the work file.
+func (w *WorkFile) Name() string {
+	return w.name
+}
+
+// Commit gives the work file the permissions and owner of the replaced or
+// source file, syncs it, renames it over the file and syncs the directory.
+func (w *WorkFile) Commit() error {
+	info, err := w.fsys.Stat(w.source)
+	if err != nil {
+		return fmt.Errorf("source file stat failed: %w", err)
+	}
+
+	if err := w.fsys.Chmod(w.name, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("work file chmod failed: %w", err)
+	}
+
+	if uid, gid, ok := libfs.Owner(info); ok {
+		if err := w.fsys.Chown(w.name, uid, gid); err != nil {
+			return fmt.Errorf("work file chown failed: %w", err)
+		}
+	}
+
+	if err := w.File.Sync(); err != nil {
+		return fmt.Errorf("work file sync failed: %w", err)
+	}
+
+	if err := w.fsys.Rename(w.name, w.path); err != nil {
+		return fmt.Errorf("work file rename failed: %w", err)
+	}
+
+	w.committed = true
+
+	if err := w.fsys.SyncDir(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("work dir sync failed: %w", err)
+	}
+
+	return nil
+}
+
+// Remove closes the work file and removes it unless it was committed.
+func (w *WorkFile) Remove() error {
+	w.File.Close()
+
+	if w.committed {
+		return nil
+	}
+
+	if err := w.fsys.Remove(w.name); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove work file failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package patcher_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grinderz/grgo/libfs"
+	"github.com/grinderz/grgo/patcher"
+)
+
+//nolint:gochecknoglobals
+var testNow = time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+func newWorkFS(t *testing.T) *libfs.MemFS {
+	t.Helper()
+
+	fsys := libfs.NewMemFS()
+	fsys.WriteFile("dir/blob", []byte("original"))
+
+	if err := fsys.Chmod("dir/blob", 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	return fsys
+}
+
+func TestWorkFileCommit(t *testing.T) {
+	t.Parallel()
+
+	fsys := newWorkFS(t)
+
+	workFile, err := patcher.CreateWorkFile(fsys, "dir/blob", ".out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := workFile.Write([]byte("patched")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := workFile.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := workFile.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := fsys.Names(); !slices.Equal(names, []string{"dir/blob"}) {
+		t.Fatalf("files non valid: %v", names)
+	}
+
+	data, err := fsys.ReadFile("dir/blob")
+	if err != nil || string(data) != "patched" {
+		t.Fatalf("content non valid: %q %v", data, err)
+	}
+
+	stat, err := fsys.Stat("dir/blob")
+	if err != nil || stat.Mode().Perm() != 0o750 {
+		t.Fatalf("mode non valid: %v %v", stat, err)
+	}
+}
+
+func TestWorkFileRemove(t *testing.T) {
+	t.Parallel()
+
+	fsys := newWorkFS(t)
+
+	workFile, err := patcher.CreateWorkFile(fsys, "dir/blob", ".out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := fsys.Names()
+	slices.Sort(names)
+
+	if len(names) != 2 || !strings.HasPrefix(names[0], "dir/.blob.") || !strings.HasSuffix(names[0], ".out") {
+		t.Fatalf("work file non valid: %v", names)
+	}
+
+	if err := workFile.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := fsys.Names(); !slices.Equal(names, []string{"dir/blob"}) {
+		t.Fatalf("work file left: %v", names)
+	}
+
+	data, err := fsys.ReadFile("dir/blob")
+	if err != nil || string(data) != "original" {
+		t.Fatalf("content non valid: %q %v", data, err)
+	}
+}